@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/flynshue/oktactl/pkg/okta-api/v2/oktaapi"
+)
+
+const (
+	severityHigh   = "high"
+	severityMedium = "medium"
+	severityLow    = "low"
+	severityInfo   = "info"
+)
+
+var severityRank = map[string]int{severityHigh: 0, severityMedium: 1, severityLow: 2, severityInfo: 3}
+
+// finding is a single problem reported by an audit command.
+type finding struct {
+	Severity     string `json:"severity"`
+	Check        string `json:"check"`
+	ResourceType string `json:"resourceType"`
+	ResourceID   string `json:"resourceId"`
+	ResourceName string `json:"resourceName,omitempty"`
+	Message      string `json:"message"`
+}
+
+// auditHygiene scans every active app and group for empty groups, groups and
+// apps without assignments, and assignments to groups that no longer exist. An app
+// without groups is only unassigned if no user is assigned to it directly either.
+func auditHygiene(os OktaService) ([]finding, error) {
+	groups, err := os.ListOktaGroups("")
+	if err != nil {
+		return nil, err
+	}
+	apps, err := os.ListApps("")
+	if err != nil {
+		return nil, err
+	}
+	findings := []finding{}
+	groupExists := map[string]bool{}
+	for _, group := range groups {
+		groupExists[group.ID] = true
+	}
+	assigned := map[string]bool{}
+	for _, app := range apps {
+		_, assignments, err := os.ListAppsGroups(app.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(assignments) == 0 {
+			direct, err := hasDirectAssignments(os, app.ID)
+			if err != nil {
+				return nil, err
+			}
+			if !direct {
+				findings = append(findings, finding{
+					Severity: severityMedium, Check: "unassigned-app", ResourceType: "app", ResourceID: app.ID, ResourceName: app.Label,
+					Message: "active app has no group or user assignments",
+				})
+			}
+		}
+		for _, assignment := range assignments {
+			assigned[assignment.GroupID] = true
+			if !groupExists[assignment.GroupID] {
+				findings = append(findings, finding{
+					Severity: severityHigh, Check: "orphaned-assignment", ResourceType: "app", ResourceID: app.ID, ResourceName: app.Label,
+					Message: fmt.Sprintf("assigned to group %s which no longer exists", assignment.GroupID),
+				})
+			}
+		}
+	}
+	for _, group := range groups {
+		users, err := os.ListOktaGroupUsers(group.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(users) == 0 {
+			findings = append(findings, finding{
+				Severity: severityLow, Check: "empty-group", ResourceType: "group", ResourceID: group.ID, ResourceName: group.Name,
+				Message: "group has no members",
+			})
+		}
+		if !assigned[group.ID] {
+			findings = append(findings, finding{
+				Severity: severityInfo, Check: "unassigned-group", ResourceType: "group", ResourceID: group.ID, ResourceName: group.Name,
+				Message: "group is not assigned to any active app",
+			})
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityRank[findings[i].Severity] < severityRank[findings[j].Severity]
+	})
+	return findings, nil
+}
+
+// hasDirectAssignments reports whether any user is assigned to appID directly rather than
+// through a group.
+func hasDirectAssignments(os OktaService, appID string) (bool, error) {
+	users, err := os.ListAppUsers(appID)
+	if err != nil {
+		return false, err
+	}
+	for _, u := range users {
+		if u.Scope == "USER" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func runAuditHygiene(w io.Writer, svc OktaService, format string) error {
+	findings, err := auditHygiene(svc)
+	if err != nil {
+		return err
+	}
+	return writeFindings(w, format, findings)
+}
+
+func writeFindings(w io.Writer, format string, findings []finding) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(findings)
+	case "table":
+		if len(findings) == 0 {
+			fmt.Fprintln(w, "no findings")
+			return nil
+		}
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.TabIndent)
+		fmt.Fprintln(tw, "Severity\t Check\t Type\t ID\t Name\t Message\t")
+		for _, f := range findings {
+			fmt.Fprintf(tw, "%s\t %s\t %s\t %s\t %s\t %s\t\n", f.Severity, f.Check, f.ResourceType, f.ResourceID, f.ResourceName, f.Message)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unsupported output format %q, must be one of table, json", format)
+	}
+}
+
+// staleUser is a user who still has access to an app but is not active or has not logged in recently.
+type staleUser struct {
+	userAccess
+	Reason string `json:"reason"`
+}
+
+type staleAppAccess struct {
+	App   oktaapi.App `json:"app"`
+	Users []staleUser `json:"users"`
+}
+
+// auditStaleUsers finds users granted app access through a group who are not
+// ACTIVE or whose last login is more than inactiveDays before now.
+func auditStaleUsers(os OktaService, inactiveDays int, now time.Time) ([]staleAppAccess, error) {
+	apps, err := os.ListApps("")
+	if err != nil {
+		return nil, err
+	}
+	cutoff := now.AddDate(0, 0, -inactiveDays)
+	results := []staleAppAccess{}
+	for _, app := range apps {
+		app, access, _, err := appAccess(os, app.ID)
+		if err != nil {
+			return nil, err
+		}
+		stale := []staleUser{}
+		for _, ua := range access {
+			if reason := staleReason(ua.User, cutoff); reason != "" {
+				stale = append(stale, staleUser{userAccess: ua, Reason: reason})
+			}
+		}
+		if len(stale) > 0 {
+			results = append(results, staleAppAccess{App: app, Users: stale})
+		}
+	}
+	return results, nil
+}
+
+// staleReason explains why user should no longer have access, or returns an empty string if they are active.
+func staleReason(user oktaapi.User, cutoff time.Time) string {
+	if user.Status != "" && user.Status != "ACTIVE" {
+		return fmt.Sprintf("status %s", user.Status)
+	}
+	if user.LastLogin == "" {
+		return "never logged in"
+	}
+	lastLogin, err := time.Parse(time.RFC3339, user.LastLogin)
+	if err != nil {
+		return fmt.Sprintf("unreadable last login %q", user.LastLogin)
+	}
+	if lastLogin.Before(cutoff) {
+		return fmt.Sprintf("no login since %s", lastLogin.Format("2006-01-02"))
+	}
+	return ""
+}
+
+func runAuditStaleUsers(w io.Writer, svc OktaService, inactiveDays int, format string) error {
+	results, err := auditStaleUsers(svc, inactiveDays, time.Now())
+	if err != nil {
+		return err
+	}
+	return writeStaleUsers(w, format, results)
+}
+
+func writeStaleUsers(w io.Writer, format string, results []staleAppAccess) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	case "table":
+		if len(results) == 0 {
+			fmt.Fprintln(w, "no stale users found")
+			return nil
+		}
+		for _, result := range results {
+			fmt.Fprintf(w, "%s %s\n", result.App.ID, result.App.Label)
+			tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.TabIndent)
+			fmt.Fprintln(tw, "  Okta User ID\t Email\t Status\t Last Login\t Reason\t Groups\t")
+			for _, u := range result.Users {
+				fmt.Fprintf(tw, "  %s\t %s\t %s\t %s\t %s\t %s\t\n", u.ID, u.Email, u.Status, u.LastLogin, u.Reason, strings.Join(u.Groups, ", "))
+			}
+			tw.Flush()
+			fmt.Fprintln(w)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format %q, must be one of table, json", format)
+	}
+}
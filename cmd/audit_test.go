@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/flynshue/oktactl/pkg/okta-api/v2/oktaapi"
+)
+
+func TestAuditHygiene(t *testing.T) {
+	snap := &oktaapi.Snapshot{
+		Apps: []oktaapi.App{
+			{ID: "0oa1gjh63g214q0Hq0g4", Label: "Test Custom Saml 2.0 App"},
+			{ID: "0oabkvBLDEKCNXBGYUAS", Label: "Test Sample Plugin App"},
+			{ID: "0oa1directonly000001", Label: "Direct Assignments Only"},
+		},
+		AppGroups: map[string][]oktaapi.GroupAssignmentResp{
+			"0oa1gjh63g214q0Hq0g4": {{GroupID: "00g1emaKYZTWRYYRRTSK"}, {GroupID: "00gdeletedgroup00000"}},
+		},
+		AppUsers: map[string][]oktaapi.AppUser{
+			"0oa1directonly000001": {{ID: "00u1emaKYZTWRYYRRTSK", Scope: "USER"}},
+		},
+		Groups: []oktaapi.Group{
+			{ID: "00g1emaKYZTWRYYRRTSK", Profile: oktaapi.Profile{Name: "Fake Group 01"}},
+			{ID: "00gg0xVALADWBPXOFZAS", Profile: oktaapi.Profile{Name: "Fake Group 02"}},
+		},
+		GroupUsers: map[string][]oktaapi.User{
+			"00g1emaKYZTWRYYRRTSK": {{ID: "00u1emaKYZTWRYYRRTSK"}},
+			"00gg0xVALADWBPXOFZAS": {},
+		},
+	}
+	findings, err := auditHygiene(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The app assigned only to a user directly is not unassigned.
+	want := []string{"orphaned-assignment", "unassigned-app", "empty-group", "unassigned-group"}
+	if len(findings) != len(want) {
+		t.Fatalf("got %d findings %v, want %v", len(findings), findings, want)
+	}
+	for i, check := range want {
+		if findings[i].Check != check {
+			t.Errorf("finding %d = %s, want %s", i, findings[i].Check, check)
+		}
+	}
+	if findings[1].ResourceID != "0oabkvBLDEKCNXBGYUAS" {
+		t.Errorf("unassigned app = %s, want the app without groups or users", findings[1].ResourceID)
+	}
+	buf := &bytes.Buffer{}
+	if err := writeFindings(buf, "json", findings); err != nil {
+		t.Fatal(err)
+	}
+	decoded := []finding{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded[0].Severity != severityHigh {
+		t.Errorf("first finding severity = %s, want %s", decoded[0].Severity, severityHigh)
+	}
+}
+
+func TestAuditStaleUsers(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	results, err := auditStaleUsers(&MockOktaClient{}, 90, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d apps, want 2", len(results))
+	}
+	reasons := map[string]string{}
+	for _, u := range results[0].Users {
+		reasons[u.Email] = u.Reason
+	}
+	want := map[string]string{
+		"user1@example.com": "status SUSPENDED",
+		"user2@example.com": "never logged in",
+	}
+	if len(reasons) != len(want) {
+		t.Errorf("got stale users %v, want %v", reasons, want)
+	}
+	for email, reason := range want {
+		if reasons[email] != reason {
+			t.Errorf("%s reason = %q, want %q", email, reasons[email], reason)
+		}
+	}
+	if err := writeStaleUsers(&bytes.Buffer{}, "table", results); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStaleReason(t *testing.T) {
+	cutoff := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	user := oktaapi.User{Status: "ACTIVE", LastLogin: "2023-12-01T10:00:00.000Z"}
+	if got := staleReason(user, cutoff); got != "no login since 2023-12-01" {
+		t.Errorf("staleReason = %q", got)
+	}
+	user.LastLogin = "2024-02-01T10:00:00.000Z"
+	if got := staleReason(user, cutoff); got != "" {
+		t.Errorf("staleReason for recent login = %q, want empty", got)
+	}
+}
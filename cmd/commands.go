@@ -17,9 +17,17 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/flynshue/oktactl/pkg/browse"
+	"github.com/flynshue/oktactl/pkg/okta-api/v2/oktaapi"
+	"github.com/flynshue/oktactl/pkg/watch"
 	"github.com/spf13/cobra"
 )
 
@@ -32,6 +40,33 @@ var (
 	Version = "unreleased"
 )
 
+var (
+	reportFormat  string
+	auditFormat   string
+	inactiveDays  int
+	sodConfigFile string
+	groupsFile    string
+	protectRules  bool
+	autoApprove   bool
+	mirrorYes     bool
+
+	terraformGroups string
+	terraformOut    string
+
+	offboardOpts  offboardOptions
+	receiptPath   string
+	restoreDryRun bool
+
+	membershipExpires time.Duration
+	reconcileLoop     bool
+	reconcileInterval time.Duration
+
+	watchFormat   string
+	watchInterval time.Duration
+
+	eventHookOpts eventHookOptions
+)
+
 var listAppsCmd = &cobra.Command{
 	Use:   "apps [name]",
 	Short: "list apps by name",
@@ -47,18 +82,25 @@ var listAppsCmd = &cobra.Command{
 		if len(args) == 0 {
 			return fmt.Errorf("must supply app name")
 		}
-		return listApps(newClient(), args[0])
+		ctx, stop := interruptContext(cmd)
+		defer stop()
+		return listApps(ctx, cmd.OutOrStdout(), newService(), args[0])
 	},
 }
 
 var listAppGroupAssignment = &cobra.Command{
-	Use:   "groups [app ID]",
+	Use:   "groups [app]",
 	Short: "List groups assigned to application",
+	Long:  "Lists the groups assigned to an application given by ID, label, name, or a label prefix that matches only one application",
+	Example: `  # List groups assigned to an app by label
+  oktactl list apps groups "Test Custom Saml"
+	`,
+	ValidArgsFunction: completeArgs("app"),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 0 {
-			return fmt.Errorf("must supply app id")
+			return fmt.Errorf("must supply app")
 		}
-		return listAppsGroups(newClient(), args[0])
+		return listAppsGroups(cmd.OutOrStdout(), newService(), args[0])
 	},
 }
 
@@ -73,21 +115,30 @@ var listGroupsCmd = &cobra.Command{
 			return fmt.Errorf("must supply group name")
 		}
 		keywords := strings.Join(args, " ")
-		return listOktaGroups(newClient(), keywords)
+		ctx, stop := interruptContext(cmd)
+		defer stop()
+		return listOktaGroups(ctx, cmd.OutOrStdout(), newService(), keywords)
 	},
 }
 
 var listGroupUsersCmd = &cobra.Command{
-	Use:   "users [group ID]",
+	Use:   "users [group]",
 	Short: "List users in group",
+	Long:  "Lists the users in a group given by ID, name, or a name prefix that matches only one group",
 	Example: ` # List users in group
   oktactl list users 00g1hqieohhlPBv581d8
+
+  # List users in group by name
+  oktactl list users "Fake Group 01"
 	`,
+	ValidArgsFunction: completeArgs("group"),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 0 {
-			return fmt.Errorf("must supply group ID")
+			return fmt.Errorf("must supply group")
 		}
-		return listOktaGroupUsers(newClient(), args[0])
+		ctx, stop := interruptContext(cmd)
+		defer stop()
+		return listOktaGroupUsers(ctx, cmd.OutOrStdout(), newService(), args[0])
 	},
 }
 
@@ -97,20 +148,606 @@ var listCmd = &cobra.Command{
 	Short: "list resources",
 }
 
+var exportCmd = &cobra.Command{
+	Use:   "export [command]",
+	Short: "export org data",
+}
+
+var exportSnapshotCmd = &cobra.Command{
+	Use:   "snapshot [dir]",
+	Short: "Export apps, groups, assignments and group members to a snapshot directory",
+	Long:  "Exports apps, groups, app group assignments and group members to a directory that can be read back with --from-snapshot to run list commands without network access",
+	Example: `  # Export the org and list apps from the export
+  oktactl export snapshot ./okta-snapshot
+  oktactl list apps test --from-snapshot ./okta-snapshot
+	`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("must supply snapshot directory")
+		}
+		return exportSnapshot(cmd.OutOrStdout(), newClient(), args[0])
+	},
+}
+
+var exportTerraformCmd = &cobra.Command{
+	Use:   "terraform",
+	Short: "Generate Terraform configuration for groups, memberships and app group assignments",
+	Long:  "Writes okta_group, okta_group_memberships and okta_app_group_assignments resources with import blocks for every Okta group whose name matches --groups. Apps assigned to a matching group keep all of their group assignments because okta_app_group_assignments is authoritative for the app.",
+	Example: `  # Generate configuration for aws groups from a snapshot
+  oktactl export terraform --groups 'aws-*' --out okta.tf --from-snapshot ./okta-snapshot
+	`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runExportTerraform(cmd.OutOrStdout(), newService(), terraformGroups, terraformOut)
+	},
+}
+
+var reportCmd = &cobra.Command{
+	Use:   "report [command]",
+	Short: "generate access reports",
+}
+
+var reportAppAccessCmd = &cobra.Command{
+	Use:   "app-access [app]",
+	Short: "Report every user with access to an application",
+	Long:  "Expands every group assigned to the application into its members and lists each user once with their status, last login, and the groups and roles that grant them access",
+	Example: `  # Write an access review for an app as markdown
+  oktactl report app-access 0oa1gjh63g214q0Hq0g4 -o markdown > access-review.md
+	`,
+	ValidArgsFunction: completeArgs("app"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("must supply app")
+		}
+		return reportAppAccess(cmd.OutOrStdout(), cmd.ErrOrStderr(), newService(), args[0], reportFormat)
+	},
+}
+
+var auditCmd = &cobra.Command{
+	Use:   "audit [command]",
+	Short: "audit org configuration",
+}
+
+var auditHygieneCmd = &cobra.Command{
+	Use:   "hygiene",
+	Short: "Find empty groups, unassigned groups and apps, and orphaned app assignments",
+	Long: `Scans every group and active app in the org and reports findings by severity:
+  high     app assignments pointing at groups that no longer exist
+  medium   active apps with no group or user assignments
+  low      groups with no members
+  info     groups not assigned to any active app`,
+	Example: `  # Export hygiene findings as JSON
+  oktactl audit hygiene -o json > findings.json
+	`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAuditHygiene(cmd.OutOrStdout(), newService(), auditFormat)
+	},
+}
+
+var auditStaleUsersCmd = &cobra.Command{
+	Use:   "stale-users",
+	Short: "Find deactivated, suspended and inactive users that still have app access",
+	Long:  "Lists users that are not ACTIVE, or have not logged in within --inactive-days, but are still members of groups assigned to active apps. Results are grouped by app.",
+	Example: `  # Find users with app access that have not logged in for 90 days
+  oktactl audit stale-users --inactive-days 90
+	`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAuditStaleUsers(cmd.OutOrStdout(), newService(), inactiveDays, auditFormat)
+	},
+}
+
+var auditCheckCmd = &cobra.Command{
+	Use:   "check [rules file]",
+	Short: "Check access rules declared in a YAML file and exit non-zero on violations",
+	Long: `Evaluates the invariants declared in a rules file against the org, or a snapshot with --from-snapshot.
+Each rule sets exactly one check:
+
+  rules:
+    - name: aws app only assigned to aws groups
+      app: 0oa1gjh63g214q0Hq0g4
+      allowedGroups: "^aws-"
+    - name: super admins stay small
+      group: 00g1emaKYZTWRYYRRTSK
+      maxMembers: 10
+    - name: admins are enrolled in mfa
+      group: 00g1emaKYZTWRYYRRTSK
+      requiredGroup: 00gg0xVALADWBPXOFZAS
+
+The command prints every violation and exits non-zero if there are any.`,
+	Example: `  # Check rules in a scheduled job
+  oktactl audit check rules.yaml -o json
+	`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("must supply rules file")
+		}
+		return runAuditCheck(cmd.OutOrStdout(), newService(), args[0], auditFormat)
+	},
+}
+
+var auditSodCmd = &cobra.Command{
+	Use:   "sod",
+	Short: "Find users that hold conflicting groups or apps",
+	Long: `Reports every user that holds both sides of a separation-of-duties conflict, with the paths that grant each side.
+App access counts direct assignments and membership of any group assigned to the app.
+
+  conflicts:
+    - name: payments approve and submit
+      a:
+        group: 00g1emaKYZTWRYYRRTSK
+      b:
+        app: 0oa1gjh63g214q0Hq0g4
+
+The file is passed with --sod-config rather than --config, which already names oktactl's own
+config file on every command. The command exits non-zero if any conflicts are found.`,
+	Example: `  # Check separation of duties conflicts
+  oktactl audit sod --sod-config sod.yaml
+	`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAuditSod(cmd.OutOrStdout(), cmd.ErrOrStderr(), newService(), sodConfigFile, auditFormat)
+	},
+}
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Show the group membership changes needed to match a groups file",
+	Long: `Compares the members listed for each managed group in a groups file with the group's current members
+and prints the users that would be added and removed. Members are listed by login, email or user ID.
+
+  groups:
+    - id: 00g1emaKYZTWRYYRRTSK
+      name: aws-admins
+      members:
+        - alice@example.com
+        - bob@example.com
+
+Every group must list its members; write members: [] for a group that should have none. A group
+without a members list, or listed twice, is an error rather than a plan to remove everyone.
+
+With --protect-rule-managed, no member is removed from a group that an active group rule adds users
+to, including members added by hand: the API does not say how a user joined a group, and oktactl
+does not evaluate rule expressions. Turn it off to remove those members.
+
+The plan is made against the live org, so --from-snapshot cannot be used.`,
+	Example: `  # Review membership changes
+  oktactl plan -f groups.yaml
+	`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireLiveOrg(cmd); err != nil {
+			return err
+		}
+		return runPlan(cmd.OutOrStdout(), newUncachedClient(), groupsFile, protectRules)
+	},
+}
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Add and remove group members to match a groups file",
+	Long:  "Prints the same plan as 'oktactl plan', asks for confirmation, then adds and removes group members so each managed group matches the groups file",
+	Example: `  # Apply membership changes without prompting
+  oktactl apply -f groups.yaml --auto-approve
+	`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireLiveOrg(cmd); err != nil {
+			return err
+		}
+		return runApply(cmd.InOrStdin(), cmd.OutOrStdout(), newAdmin(commandLine(cmd, args)), groupsFile, protectRules, autoApprove)
+	},
+}
+
+var userCmd = &cobra.Command{
+	Use:   "user [command]",
+	Short: "manage user access",
+}
+
+var userMirrorCmd = &cobra.Command{
+	Use:   "mirror [source user] [target user]",
+	Short: "Give a user the same direct group memberships as another user",
+	Long:  "Adds the target user to every group the source user is directly a member of and the target is not. Built-in, app and rule-managed groups are skipped. Users are given by login or user ID.",
+	Example: `  # Give bob the same access as alex
+  oktactl user mirror alex@example.com bob@example.com
+	`,
+	ValidArgsFunction: completeArgs("user", "user"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireLiveOrg(cmd); err != nil {
+			return err
+		}
+		if len(args) < 2 {
+			return fmt.Errorf("must supply source and target user")
+		}
+		return runUserMirror(cmd.InOrStdin(), cmd.OutOrStdout(), newAdmin(commandLine(cmd, args)), args[0], args[1], mirrorYes)
+	},
+}
+
+var userOffboardCmd = &cobra.Command{
+	Use:   "offboard [user]",
+	Short: "Remove a user's group memberships and sessions and write a restorable receipt",
+	Long: `Offboards a user in one audited run:
+  - records the user's groups and apps
+  - removes the user from every group they are directly a member of
+  - clears the user's sessions and OAuth tokens
+  - optionally suspends or deactivates the user
+  - writes a JSON receipt that 'oktactl user restore' can use to undo the offboarding
+Built-in, app and rule-managed group memberships are recorded but not removed.`,
+	Example: `  # Preview offboarding
+  oktactl user offboard alex@example.com --dry-run
+
+  # Offboard and suspend, writing the receipt to a known path
+  oktactl user offboard alex@example.com --suspend --receipt alex-offboard.json
+	`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completeArgs("user"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireLiveOrg(cmd); err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("must supply user")
+		}
+		return runUserOffboard(cmd.OutOrStdout(), newAdmin(commandLine(cmd, args)), args[0], offboardOpts, receiptPath)
+	},
+}
+
+var userRestoreCmd = &cobra.Command{
+	Use:   "restore [receipt]",
+	Short: "Restore a user's access from an offboarding receipt",
+	Long: `Unsuspends or reactivates the user if offboarding changed their status, then adds them back to every group that offboarding removed them from.
+
+A reactivated user is PROVISIONED, not ACTIVE, until they set a password. No email is sent to them, so
+the command says when that is left to do: send them an activation email with Resend Activation Email
+on their page in the Okta Admin Console.`,
+	Example: `  # Undo an offboarding
+  oktactl user restore alex-offboard.json
+	`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireLiveOrg(cmd); err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("must supply receipt file")
+		}
+		return runUserRestore(cmd.OutOrStdout(), newAdmin(commandLine(cmd, args)), args[0], restoreDryRun)
+	},
+}
+
+var journalCmd = &cobra.Command{
+	Use:   "journal [command]",
+	Short: "list and roll back changes made by oktactl",
+	Long:  "Every change oktactl makes to the org is recorded with the change that undoes it in an append-only journal, $HOME/.oktactl/journal.jsonl unless the journal config key is set. Changes made by one command run share an operation ID.",
+}
+
+var journalListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List journaled operations",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runJournalList(cmd.OutOrStdout(), openJournal())
+	},
+}
+
+var journalRollbackCmd = &cobra.Command{
+	Use:   "rollback [operation ID]",
+	Short: "Undo every change made by a journaled operation",
+	Long:  "Applies the inverse of every change in the operation, newest first. Changes that cannot be undone, such as clearing sessions, are skipped. The rollback is itself journaled, and if it fails partway, running it again resumes it, skipping the changes already undone.",
+	Example: `  # Find and undo a bad apply
+  oktactl journal list
+  oktactl journal rollback 20240301T101500-a1b2c3
+	`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireLiveOrg(cmd); err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("must supply operation ID")
+		}
+		return runJournalRollback(cmd.OutOrStdout(), newUncachedClient(), openJournal(), args[0])
+	},
+}
+
+var groupCmd = &cobra.Command{
+	Use:   "group [command]",
+	Short: "manage group membership",
+}
+
+var groupAddUserCmd = &cobra.Command{
+	Use:   "add-user [group] [user]",
+	Short: "Add a user to a group, optionally for a limited time",
+	Long:  "Adds the user to the group. With --expires the membership is recorded in $HOME/.oktactl/expirations.json, or the file named by the expirations config key, and 'oktactl reconcile expirations' removes it once it expires. Running add-user again for a time-bound membership replaces its expiry.",
+	Example: `  # Grant access to a group for three days
+  oktactl group add-user 00gg0xVALADWBPXOFZAS alex@example.com --expires 72h
+	`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completeArgs("group", "user"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireLiveOrg(cmd); err != nil {
+			return err
+		}
+		if len(args) < 2 {
+			return fmt.Errorf("must supply group ID and user")
+		}
+		return runGroupAddUser(cmd.OutOrStdout(), newAdmin(commandLine(cmd, args)), expirationsPath(), args[0], args[1], membershipExpires)
+	},
+}
+
+var reconcileCmd = &cobra.Command{
+	Use:   "reconcile [command]",
+	Short: "bring the org in line with oktactl's local state",
+}
+
+var reconcileExpirationsCmd = &cobra.Command{
+	Use:   "expirations",
+	Short: "Remove group memberships whose expiry has passed",
+	Long:  "Removes every time-bound membership added with 'oktactl group add-user --expires' that has expired and logs each removal. Removals are journaled. Memberships that cannot be removed are kept and retried on the next run.",
+	Example: `  # Remove expired memberships once, e.g. from cron
+  oktactl reconcile expirations
+
+  # Keep running, checking every five minutes
+  oktactl reconcile expirations --loop --interval 5m
+	`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireLiveOrg(cmd); err != nil {
+			return err
+		}
+		client, j, command := newUncachedClient(), openJournal(), commandLine(cmd, args)
+		newPassAdmin := func() OktaGroupAdmin { return newJournaledAdmin(client, j, command) }
+		return runReconcileExpirations(cmd.OutOrStdout(), newPassAdmin, expirationsPath(), reconcileLoop, reconcileInterval)
+	},
+}
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [command]",
+	Short: "print membership changes of a group or app as they happen",
+	Long:  "Polls a group or app at an interval and prints an event for every member added or removed since the previous poll. Events go to stdout as a table or NDJSON; progress and errors go to stderr. Events are also sent to the webhooks routed to the group or app under notify in the config file. Runs until interrupted. A snapshot never changes, so --from-snapshot cannot be used.",
+}
+
+var watchGroupCmd = &cobra.Command{
+	Use:   "group [group]",
+	Short: "Watch the users in a group",
+	Example: `  # Alert on changes to the super admins group
+  oktactl watch group 00g1emaKYZTWRYYRRTSK --interval 1m -o ndjson
+	`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completeArgs("group"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireLiveOrg(cmd); err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("must supply group")
+		}
+		svc := newUncachedClient()
+		id, err := oktaapi.ResolveGroupID(svc, args[0])
+		if err != nil {
+			return err
+		}
+		n, err := newNotifier()
+		if err != nil {
+			return err
+		}
+		return runWatch(cmd.OutOrStdout(), watch.Group, id, groupMembers(svc, id), n, watchInterval, watchFormat)
+	},
+}
+
+var watchAppCmd = &cobra.Command{
+	Use:   "app [app]",
+	Short: "Watch the groups assigned to an app",
+	Example: `  # Watch group assignments of an app
+  oktactl watch app 0oa1gjh63g214q0Hq0g4
+	`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completeArgs("app"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireLiveOrg(cmd); err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("must supply app")
+		}
+		svc := newUncachedClient()
+		id, err := oktaapi.ResolveAppID(svc, args[0])
+		if err != nil {
+			return err
+		}
+		n, err := newNotifier()
+		if err != nil {
+			return err
+		}
+		return runWatch(cmd.OutOrStdout(), watch.App, id, appMembers(svc, id), n, watchInterval, watchFormat)
+	},
+}
+
+var serveCmd = &cobra.Command{
+	Use:   "serve [command]",
+	Short: "run oktactl as a server",
+}
+
+var serveEventHookCmd = &cobra.Command{
+	Use:   "event-hook",
+	Short: "Receive Okta event hooks",
+	Long: `Serves an Okta event hook endpoint. Okta's one-time verification request is answered and every
+delivery must carry the authorization header value configured on the event hook, which oktactl reads
+from event_hook_secret in the config file or the EVENT_HOOK_SECRET environment variable.
+
+Each event is written as a line of NDJSON to stdout or --out. With --notify, group membership and
+app assignment events are also sent to the webhooks routed to the group or app under notify in the config file.`,
+	Example: `  # Receive events behind a TLS-terminating proxy
+  EVENT_HOOK_SECRET=s3cret oktactl serve event-hook --listen :8443
+
+  # Terminate TLS, append events to a file and notify on membership changes
+  oktactl serve event-hook --listen :8443 --tls-cert cert.pem --tls-key key.pem --out events.ndjson --notify
+	`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runServeEventHook(cmd.OutOrStdout(), eventHookOpts)
+	},
+}
+
+var compareCmd = &cobra.Command{
+	Use:   "compare [command]",
+	Short: "compare access between users or groups",
+}
+
+var compareUsersCmd = &cobra.Command{
+	Use:   "users [user] [user]",
+	Short: "Show the groups and apps each user has that the other does not",
+	Long:  "Shows the groups and apps each user has that the other does not. Snapshots do not record users' app assignments, so the users are compared in the live org and --from-snapshot cannot be used.",
+	Example: `  # Compare two users by login
+  oktactl compare users alex@example.com bob@example.com
+	`,
+	ValidArgsFunction: completeArgs("user", "user"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireLiveOrg(cmd); err != nil {
+			return err
+		}
+		if len(args) < 2 {
+			return fmt.Errorf("must supply two users")
+		}
+		return runCompareUsers(cmd.OutOrStdout(), newClient(), args[0], args[1])
+	},
+}
+
+var compareGroupsCmd = &cobra.Command{
+	Use:   "groups [group] [group]",
+	Short: "Show the members of each group that are not in the other",
+	Example: `  # Compare the members of two groups
+  oktactl compare groups 00g1emaKYZTWRYYRRTSK 00gg0xVALADWBPXOFZAS
+	`,
+	ValidArgsFunction: completeArgs("group", "group"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 2 {
+			return fmt.Errorf("must supply two group IDs")
+		}
+		return runCompareGroups(cmd.OutOrStdout(), newService(), args[0], args[1])
+	},
+}
+
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish]",
+	Short: "Generate a shell completion script",
+	Long: `Writes a completion script for bash, zsh or fish to stdout. Groups, apps and users are
+completed from the org as you type, with results cached for a minute in $HOME/.oktactl/cache/completion.
+
+Bash needs the bash-completion package. Load completions in the current shell with
+
+  source <(oktactl completion bash)
+
+and for every new shell add the same line to ~/.bashrc. For zsh, add
+
+  source <(oktactl completion zsh)
+
+to ~/.zshrc, after compinit. For fish, run
+
+  oktactl completion fish > ~/.config/fish/completions/oktactl.fish`,
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return writeCompletionScript(cmd.OutOrStdout(), cmd.Root(), args[0])
+	},
+}
+
+var uiCmd = &cobra.Command{
+	Use:   "ui",
+	Short: "Browse apps, groups and users interactively",
+	Long: `Opens a terminal UI to search apps or groups as you type, and drill down from an app into its
+assigned groups, from a group into its members, and from a user into their other groups.
+
+Keys: enter opens the selected row and backspace goes back, / searches and tab switches between
+apps and groups, c copies the selected ID to the clipboard, e and E export the current view to
+csv and json in the current directory, and q quits. Copying uses OSC 52, which most terminals
+support, including over SSH.`,
+	Example: `  # Browse the org
+  oktactl ui
+
+  # Browse a snapshot offline
+  oktactl ui --from-snapshot ./okta-snapshot
+	`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		src, ok := newService().(browse.Source)
+		if !ok {
+			return fmt.Errorf("this data source cannot be browsed")
+		}
+		return runUI(src)
+	},
+}
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Show version for oktactl",
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Version:\t", Version)
-		fmt.Println("Git commit:\t", GitCommit)
-		fmt.Println("Date:\t\t", BuildDate)
+		fmt.Fprintln(cmd.OutOrStdout(), "Version:\t", Version)
+		fmt.Fprintln(cmd.OutOrStdout(), "Git commit:\t", GitCommit)
+		fmt.Fprintln(cmd.OutOrStdout(), "Date:\t\t", BuildDate)
 	},
 }
 
+// commandLine describes a command run for the journal.
+func commandLine(cmd *cobra.Command, args []string) string {
+	return strings.TrimSpace(cmd.CommandPath() + " " + strings.Join(args, " "))
+}
+
+// requireLiveOrg fails commands that must read the live org when --from-snapshot is set, rather
+// than silently ignoring the snapshot.
+func requireLiveOrg(cmd *cobra.Command) error {
+	if snapshotDir != "" {
+		return fmt.Errorf("%s reads the live org and cannot be used with --from-snapshot", cmd.CommandPath())
+	}
+	return nil
+}
+
+// interruptContext returns the command's context, canceled when the command is interrupted so
+// that it stops fetching pages it will not print.
+func interruptContext(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+}
+
 func init() {
-	rootCmd.AddCommand(listCmd, versionCmd)
+	rootCmd.AddCommand(listCmd, auditCmd, compareCmd, completionCmd, exportCmd, groupCmd, journalCmd, reconcileCmd, reportCmd, planCmd, applyCmd, serveCmd, uiCmd, userCmd, watchCmd, versionCmd)
 	listCmd.AddCommand(listAppsCmd, listGroupsCmd, listGroupUsersCmd)
 	listAppsCmd.AddCommand(listAppGroupAssignment)
+	exportCmd.AddCommand(exportSnapshotCmd, exportTerraformCmd)
+	reportCmd.AddCommand(reportAppAccessCmd)
+	auditCmd.AddCommand(auditHygieneCmd, auditStaleUsersCmd, auditCheckCmd, auditSodCmd)
+	userCmd.AddCommand(userMirrorCmd, userOffboardCmd, userRestoreCmd)
+	compareCmd.AddCommand(compareUsersCmd, compareGroupsCmd)
+	journalCmd.AddCommand(journalListCmd, journalRollbackCmd)
+	groupCmd.AddCommand(groupAddUserCmd)
+	reconcileCmd.AddCommand(reconcileExpirationsCmd)
+	watchCmd.AddCommand(watchGroupCmd, watchAppCmd)
+	serveCmd.AddCommand(serveEventHookCmd)
+
+	reportAppAccessCmd.Flags().StringVarP(&reportFormat, "output", "o", "csv", "output format, one of csv, markdown, html")
+	auditCmd.PersistentFlags().StringVarP(&auditFormat, "output", "o", "table", "output format, one of table, json")
+	auditStaleUsersCmd.Flags().IntVar(&inactiveDays, "inactive-days", 90, "days since last login after which a user is considered inactive")
+	auditSodCmd.Flags().StringVar(&sodConfigFile, "sod-config", "", "file listing the conflicting groups and apps")
+	auditSodCmd.MarkFlagRequired("sod-config")
+	for _, c := range []*cobra.Command{planCmd, applyCmd} {
+		c.Flags().StringVarP(&groupsFile, "file", "f", "", "groups file listing the desired members of each managed group")
+		c.Flags().BoolVar(&protectRules, "protect-rule-managed", true, "never remove members, even ones added by hand, from groups that an active group rule assigns users to")
+		c.MarkFlagRequired("file")
+	}
+	applyCmd.Flags().BoolVar(&autoApprove, "auto-approve", false, "apply changes without asking for confirmation")
+	userMirrorCmd.Flags().BoolVarP(&mirrorYes, "yes", "y", false, "add the target user without asking for confirmation")
+	userOffboardCmd.Flags().BoolVar(&offboardOpts.DryRun, "dry-run", false, "show what would be done without changing anything")
+	userOffboardCmd.Flags().BoolVar(&offboardOpts.Suspend, "suspend", false, "suspend the user after removing access")
+	userOffboardCmd.Flags().BoolVar(&offboardOpts.Deactivate, "deactivate", false, "deactivate the user after removing access")
+	userOffboardCmd.Flags().StringVar(&receiptPath, "receipt", "", "file to write the receipt to (default is offboard-<user ID>-<time>.json)")
+	userRestoreCmd.Flags().BoolVar(&restoreDryRun, "dry-run", false, "show what would be restored without changing anything")
+	exportTerraformCmd.Flags().StringVar(&terraformGroups, "groups", "*", "glob matched against group names, e.g. 'aws-*'")
+	groupAddUserCmd.Flags().DurationVar(&membershipExpires, "expires", 0, "remove the membership after this long, e.g. 72h (default is permanent)")
+	reconcileExpirationsCmd.Flags().BoolVar(&reconcileLoop, "loop", false, "keep running and reconcile every --interval until interrupted")
+	reconcileExpirationsCmd.Flags().DurationVar(&reconcileInterval, "interval", 5*time.Minute, "time between reconciliations with --loop")
+	watchCmd.PersistentFlags().StringVarP(&watchFormat, "output", "o", "table", "output format, one of table, ndjson")
+	watchCmd.PersistentFlags().DurationVar(&watchInterval, "interval", 30*time.Second, "time between polls")
+	serveEventHookCmd.Flags().StringVar(&eventHookOpts.Listen, "listen", ":8443", "address to listen on")
+	serveEventHookCmd.Flags().StringVar(&eventHookOpts.Out, "out", "-", "file to append events to, - for stdout")
+	serveEventHookCmd.Flags().BoolVar(&eventHookOpts.Notify, "notify", false, "send membership changes to the configured notification sinks")
+	serveEventHookCmd.Flags().StringVar(&eventHookOpts.TLSCert, "tls-cert", "", "TLS certificate file; serves plain HTTP if not set")
+	serveEventHookCmd.Flags().StringVar(&eventHookOpts.TLSKey, "tls-key", "", "TLS private key file")
+	exportTerraformCmd.Flags().StringVar(&terraformOut, "out", "", "file to write the configuration to (default is stdout)")
 
 	// Here you will define your flags and configuration settings.
 
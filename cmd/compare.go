@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/flynshue/oktactl/pkg/okta-api/v2/oktaapi"
+)
+
+// userComparison holds the groups and apps that only one of two users has.
+type userComparison struct {
+	A, B        oktaapi.User
+	OnlyAGroups []oktaapi.Group
+	OnlyBGroups []oktaapi.Group
+	OnlyAApps   []oktaapi.App
+	OnlyBApps   []oktaapi.App
+}
+
+func compareUsers(lookup OktaUserLookup, refA, refB string) (*userComparison, error) {
+	a, err := oktaapi.ResolveUser(lookup, refA)
+	if err != nil {
+		return nil, fmt.Errorf("user %s: %w", refA, err)
+	}
+	b, err := oktaapi.ResolveUser(lookup, refB)
+	if err != nil {
+		return nil, fmt.Errorf("user %s: %w", refB, err)
+	}
+	groupsA, err := lookup.ListOktaUserGroups(a.ID)
+	if err != nil {
+		return nil, err
+	}
+	groupsB, err := lookup.ListOktaUserGroups(b.ID)
+	if err != nil {
+		return nil, err
+	}
+	appsA, err := lookup.ListOktaUserApps(a.ID)
+	if err != nil {
+		return nil, err
+	}
+	appsB, err := lookup.ListOktaUserApps(b.ID)
+	if err != nil {
+		return nil, err
+	}
+	groupID := func(g oktaapi.Group) string { return g.ID }
+	appID := func(app oktaapi.App) string { return app.ID }
+	return &userComparison{
+		A:           a,
+		B:           b,
+		OnlyAGroups: difference(groupsA, groupsB, groupID),
+		OnlyBGroups: difference(groupsB, groupsA, groupID),
+		OnlyAApps:   difference(appsA, appsB, appID),
+		OnlyBApps:   difference(appsB, appsA, appID),
+	}, nil
+}
+
+// compareGroups returns the members of g1 that are not in g2, and the members of g2 that are not in g1.
+func compareGroups(os OktaService, g1, g2 string) ([]oktaapi.User, []oktaapi.User, error) {
+	users1, err := os.ListOktaGroupUsers(g1)
+	if err != nil {
+		return nil, nil, fmt.Errorf("group %s: %w", g1, err)
+	}
+	users2, err := os.ListOktaGroupUsers(g2)
+	if err != nil {
+		return nil, nil, fmt.Errorf("group %s: %w", g2, err)
+	}
+	userID := func(u oktaapi.User) string { return u.ID }
+	return difference(users1, users2, userID), difference(users2, users1, userID), nil
+}
+
+// difference returns the items in a whose key is not the key of any item in b, keeping the order of a.
+func difference[T any](a, b []T, key func(T) string) []T {
+	inB := map[string]bool{}
+	for _, item := range b {
+		inB[key(item)] = true
+	}
+	only := []T{}
+	for _, item := range a {
+		if !inB[key(item)] {
+			only = append(only, item)
+		}
+	}
+	return only
+}
+
+func writeUserComparison(w io.Writer, c *userComparison) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.TabIndent)
+	for _, side := range []struct {
+		user, other oktaapi.User
+		groups      []oktaapi.Group
+		apps        []oktaapi.App
+	}{
+		{c.A, c.B, c.OnlyAGroups, c.OnlyAApps},
+		{c.B, c.A, c.OnlyBGroups, c.OnlyBApps},
+	} {
+		fmt.Fprintf(tw, "Groups %s has that %s does not: %d\n", userLabel(side.user), userLabel(side.other), len(side.groups))
+		for _, group := range side.groups {
+			fmt.Fprintf(tw, "  %s\t %s\t\n", group.ID, group.Name)
+		}
+		fmt.Fprintf(tw, "Apps %s has that %s does not: %d\n", userLabel(side.user), userLabel(side.other), len(side.apps))
+		for _, app := range side.apps {
+			fmt.Fprintf(tw, "  %s\t %s\t\n", app.ID, app.Label)
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+func writeGroupComparison(w io.Writer, g1, g2 string, only1, only2 []oktaapi.User) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.TabIndent)
+	for _, side := range []struct {
+		group, other string
+		users        []oktaapi.User
+	}{
+		{g1, g2, only1},
+		{g2, g1, only2},
+	} {
+		fmt.Fprintf(tw, "Members of %s not in %s: %d\n", side.group, side.other, len(side.users))
+		for _, user := range side.users {
+			fmt.Fprintf(tw, "  %s\t %s\t %s\t %s\t\n", user.ID, user.FirstName, user.LastName, user.Email)
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+func runCompareUsers(w io.Writer, lookup OktaUserLookup, refA, refB string) error {
+	c, err := compareUsers(lookup, refA, refB)
+	if err != nil {
+		return err
+	}
+	return writeUserComparison(w, c)
+}
+
+func runCompareGroups(w io.Writer, svc OktaService, ref1, ref2 string) error {
+	g1, err := oktaapi.ResolveGroupID(svc, ref1)
+	if err != nil {
+		return err
+	}
+	g2, err := oktaapi.ResolveGroupID(svc, ref2)
+	if err != nil {
+		return err
+	}
+	only1, only2, err := compareGroups(svc, g1, g2)
+	if err != nil {
+		return err
+	}
+	return writeGroupComparison(w, g1, g2, only1, only2)
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/flynshue/oktactl/pkg/okta-api/v2/oktaapi"
+)
+
+func TestCompareUsers(t *testing.T) {
+	c, err := compareUsers(&MockOktaClient{}, "source@example.com", "target@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.OnlyAGroups) != 3 || len(c.OnlyBGroups) != 0 {
+		t.Errorf("groups only source = %d, only target = %d, want 3 and 0", len(c.OnlyAGroups), len(c.OnlyBGroups))
+	}
+	if len(c.OnlyAApps) != 1 || c.OnlyAApps[0].ID != "0oabkvBLDEKCNXBGYUAS" || len(c.OnlyBApps) != 0 {
+		t.Errorf("apps only source = %v, only target = %v", c.OnlyAApps, c.OnlyBApps)
+	}
+	buf := &bytes.Buffer{}
+	if err := writeUserComparison(buf, c); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "Apps source@example.com (00usource) has that target@example.com (00utarget) does not: 1") {
+		t.Errorf("unexpected comparison:\n%s", buf.String())
+	}
+}
+
+func TestCompareGroups(t *testing.T) {
+	snap := &oktaapi.Snapshot{GroupUsers: map[string][]oktaapi.User{
+		"00g1emaKYZTWRYYRRTSK": {{ID: "00u1"}, {ID: "00u2"}},
+		"00gg0xVALADWBPXOFZAS": {{ID: "00u2"}, {ID: "00u3"}},
+	}}
+	only1, only2, err := compareGroups(snap, "00g1emaKYZTWRYYRRTSK", "00gg0xVALADWBPXOFZAS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(only1) != 1 || only1[0].ID != "00u1" || len(only2) != 1 || only2[0].ID != "00u3" {
+		t.Errorf("only1 = %v, only2 = %v", only1, only2)
+	}
+}
+
+func TestCompareUsersFromSnapshot(t *testing.T) {
+	assertRequiresLiveOrg(t, "compare", "users", "source@example.com", "target@example.com")
+}
@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/flynshue/oktactl/pkg/okta-api/v2/oktaapi"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// completionCacheTTL is how long completions fetched from Okta are reused. It only needs to
+// cover the few tab presses it takes to complete one argument.
+const completionCacheTTL = time.Minute
+
+// completer returns the completions for a partly typed argument. Each completion is a value,
+// optionally followed by a tab and a description.
+type completer func(toComplete string) ([]string, error)
+
+// completeArgs returns a ValidArgsFunction completing the nth argument as the nth kind, one of
+// group, app or user. Results are cached on disk per org so that repeated tab presses do not
+// wait on the API.
+func completeArgs(kinds ...string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) >= len(kinds) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		kind := kinds[len(args)]
+		complete := completerFor(kind)
+		if complete == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		cache, err := newCompletionCache()
+		if err != nil {
+			cobra.CompDebugln(err.Error(), true)
+			cache = nil
+		}
+		query := toComplete
+		if prefix := idPrefixes[kind]; prefix != "" && isIDPrefix(toComplete, prefix) {
+			// Every ID is offered, so the org is listed once under one key and filtered here
+			// rather than listed again for each character typed.
+			query = ""
+		}
+		completions, err := cache.get(completionSource()+"\x00"+kind+"\x00"+query, func() ([]string, error) {
+			return complete(query)
+		})
+		if err != nil {
+			cobra.CompErrorln(err.Error())
+			return nil, cobra.ShellCompDirectiveError
+		}
+		if query != toComplete {
+			completions = withPrefix(completions, toComplete)
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// idPrefixes are the ID prefixes of the kinds that are completed by ID when no name is typed.
+var idPrefixes = map[string]string{"group": "00g", "app": "0oa"}
+
+// withPrefix returns the completions whose value starts with prefix.
+func withPrefix(completions []string, prefix string) []string {
+	matching := []string{}
+	for _, c := range completions {
+		if strings.HasPrefix(c, prefix) {
+			matching = append(matching, c)
+		}
+	}
+	return matching
+}
+
+func completerFor(kind string) completer {
+	switch kind {
+	case "group":
+		return func(toComplete string) ([]string, error) { return groupCompletions(newService(), toComplete) }
+	case "app":
+		return func(toComplete string) ([]string, error) { return appCompletions(newService(), toComplete) }
+	case "user":
+		if snapshotDir != "" {
+			return nil
+		}
+		return func(toComplete string) ([]string, error) { return userCompletions(newClient(), toComplete) }
+	}
+	return nil
+}
+
+// completionSource identifies where completions come from, so that cached results for one org
+// or snapshot are never offered for another.
+func completionSource() string {
+	switch {
+	case snapshotDir != "":
+		return "snapshot:" + snapshotDir
+	case replayDir != "":
+		return "replay:" + replayDir
+	}
+	return viper.GetString("org")
+}
+
+// isIDPrefix reports whether s could be the start of an Okta ID with the given prefix.
+func isIDPrefix(s, prefix string) bool {
+	if len(s) < len(prefix) {
+		return strings.HasPrefix(prefix, s)
+	}
+	return strings.HasPrefix(s, prefix) && !strings.ContainsAny(s, " \t")
+}
+
+// groupCompletions completes a group argument. A typed name is completed with a startsWith
+// search to the names of matching groups; otherwise every group's ID is offered with its name
+// as the description. Commands accept either, see oktaapi.ResolveGroupID.
+func groupCompletions(svc OktaService, toComplete string) ([]string, error) {
+	byID := isIDPrefix(toComplete, "00g")
+	search := toComplete
+	if byID {
+		search = ""
+	}
+	groups, err := svc.ListOktaGroups(search)
+	if err != nil {
+		return nil, err
+	}
+	completions := []string{}
+	for _, g := range groups {
+		if byID {
+			completions = append(completions, g.ID+"\t"+g.Name)
+		} else {
+			completions = append(completions, g.Name+"\t"+g.ID)
+		}
+	}
+	return completions, nil
+}
+
+// appCompletions completes an app argument like groupCompletions, using app labels.
+func appCompletions(svc OktaService, toComplete string) ([]string, error) {
+	byID := isIDPrefix(toComplete, "0oa")
+	search := toComplete
+	if byID {
+		search = ""
+	}
+	apps, err := svc.ListApps(search)
+	if err != nil {
+		return nil, err
+	}
+	completions := []string{}
+	for _, app := range apps {
+		if byID {
+			completions = append(completions, app.ID+"\t"+app.Label)
+		} else if hasPrefixFold(app.Label, toComplete) {
+			completions = append(completions, app.Label+"\t"+app.ID)
+		} else {
+			completions = append(completions, app.Name+"\t"+app.Label+" "+app.ID)
+		}
+	}
+	return completions, nil
+}
+
+// userCompletions completes a user argument to the logins that start with what was typed.
+// Nothing is offered until something is typed, since orgs can have many users.
+func userCompletions(users oktaapi.UserFinder, toComplete string) ([]string, error) {
+	if toComplete == "" {
+		return []string{}, nil
+	}
+	found, err := users.ListOktaUsers(fmt.Sprintf("profile.login sw %q", toComplete))
+	if err != nil {
+		return nil, err
+	}
+	completions := []string{}
+	for _, u := range found {
+		completions = append(completions, fmt.Sprintf("%s\t%s %s %s", u.Login, u.FirstName, u.LastName, u.ID))
+	}
+	return completions, nil
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// completionCache keeps completions in small JSON files, one per query. A nil cache fetches
+// every time.
+type completionCache struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+}
+
+type cachedCompletions struct {
+	Key         string    `json:"key"`
+	Fetched     time.Time `json:"fetched"`
+	Completions []string  `json:"completions"`
+}
+
+func newCompletionCache() (*completionCache, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	return &completionCache{dir: filepath.Join(home, ".oktactl", "cache", "completion"), ttl: completionCacheTTL, now: time.Now}, nil
+}
+
+// get returns the completions cached for key if they are fresh, or fetches and caches them.
+// Failing to read or write the cache only costs speed, so those errors are not returned.
+func (c *completionCache) get(key string, fetch func() ([]string, error)) ([]string, error) {
+	if c == nil {
+		return fetch()
+	}
+	sum := sha256.Sum256([]byte(key))
+	path := filepath.Join(c.dir, hex.EncodeToString(sum[:16])+".json")
+	if b, err := os.ReadFile(path); err == nil {
+		cached := cachedCompletions{}
+		if json.Unmarshal(b, &cached) == nil && cached.Key == key && c.now().Sub(cached.Fetched) < c.ttl {
+			return cached.Completions, nil
+		}
+	}
+	completions, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	c.prune()
+	b, err := json.Marshal(cachedCompletions{Key: key, Fetched: c.now(), Completions: completions})
+	if err == nil && os.MkdirAll(c.dir, 0o700) == nil {
+		if err := os.WriteFile(path, b, 0o600); err != nil {
+			cobra.CompDebugln(err.Error(), true)
+		}
+	}
+	return completions, nil
+}
+
+// prune removes expired entries so the cache does not grow with every prefix ever typed.
+func (c *completionCache) prune() {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		info, err := e.Info()
+		if err == nil && strings.HasSuffix(e.Name(), ".json") && c.now().Sub(info.ModTime()) >= c.ttl {
+			os.Remove(filepath.Join(c.dir, e.Name()))
+		}
+	}
+}
+
+// writeCompletionScript writes the completion script for shell.
+func writeCompletionScript(w io.Writer, root *cobra.Command, shell string) error {
+	switch shell {
+	case "bash":
+		return root.GenBashCompletionV2(w, true)
+	case "zsh":
+		return root.GenZshCompletion(w)
+	case "fish":
+		return root.GenFishCompletion(w, true)
+	}
+	return fmt.Errorf("unsupported shell %q, must be bash, zsh or fish", shell)
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flynshue/oktactl/pkg/okta-api/v2/oktaapi"
+)
+
+func TestGroupCompletions(t *testing.T) {
+	for toComplete, want := range map[string][]string{
+		"":     {"00g1emaKYZTWRYYRRTSK\tFake Group 01", "00gg0xVALADWBPXOFZAS\tFake Group 02", "00gg0xVALADWBPXOFZAK\tFake Group 03"},
+		"00gg": {"00g1emaKYZTWRYYRRTSK\tFake Group 01", "00gg0xVALADWBPXOFZAS\tFake Group 02", "00gg0xVALADWBPXOFZAK\tFake Group 03"},
+		"Fake": {"Fake Group 01\t00g1emaKYZTWRYYRRTSK", "Fake Group 02\t00gg0xVALADWBPXOFZAS", "Fake Group 03\t00gg0xVALADWBPXOFZAK"},
+	} {
+		got, err := groupCompletions(&MockOktaClient{}, toComplete)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("groupCompletions(%q) = %q, want %q", toComplete, got, want)
+		}
+	}
+}
+
+func TestAppCompletions(t *testing.T) {
+	for toComplete, want := range map[string][]string{
+		"0o":     {"0oa1gjh63g214q0Hq0g4\tTest Custom Saml 2.0 App", "0oabkvBLDEKCNXBGYUAS\tTest Sample Plugin App"},
+		"test":   {"Test Custom Saml 2.0 App\t0oa1gjh63g214q0Hq0g4", "Test Sample Plugin App\t0oabkvBLDEKCNXBGYUAS"},
+		"templa": {"testorgone_customsaml20app_1\tTest Custom Saml 2.0 App 0oa1gjh63g214q0Hq0g4", "template_swa\tTest Sample Plugin App 0oabkvBLDEKCNXBGYUAS"},
+	} {
+		got, err := appCompletions(&MockOktaClient{}, toComplete)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("appCompletions(%q) = %q, want %q", toComplete, got, want)
+		}
+	}
+}
+
+func TestUserCompletions(t *testing.T) {
+	client, _ := newFakeOrg(t)
+	got, err := userCompletions(client, "bo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"bob@example.com\tBob Builder 00u1bobb000000000002"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("userCompletions(bo) = %q, want %q", got, want)
+	}
+	if got, err := userCompletions(client, ""); err != nil || len(got) != 0 {
+		t.Errorf("userCompletions() = %q, %v, want nothing", got, err)
+	}
+}
+
+func TestCompletionCache(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	cache := &completionCache{dir: t.TempDir(), ttl: time.Minute, now: func() time.Time { return now }}
+	fetches := 0
+	fetch := func() ([]string, error) {
+		fetches++
+		return []string{"Fake Group 01\t00g1emaKYZTWRYYRRTSK"}, nil
+	}
+	for i := 0; i < 2; i++ {
+		got, err := cache.get("org\x00group\x00Fake", fetch)
+		if err != nil || len(got) != 1 {
+			t.Fatalf("get = %q, %v", got, err)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("fetched %d times, want 1", fetches)
+	}
+	if _, err := cache.get("org\x00group\x00Other", fetch); err != nil || fetches != 2 {
+		t.Errorf("a different key was served from the cache")
+	}
+	now = now.Add(time.Minute)
+	if _, err := cache.get("org\x00group\x00Fake", fetch); err != nil || fetches != 3 {
+		t.Errorf("an expired entry was served from the cache")
+	}
+	if _, err := cache.get("org\x00group\x00Failing", func() ([]string, error) { return nil, errors.New("boom") }); err == nil {
+		t.Error("expected fetch error")
+	}
+	var nilCache *completionCache
+	if _, err := nilCache.get("org\x00group\x00Fake", fetch); err != nil || fetches != 4 {
+		t.Error("a nil cache did not fetch")
+	}
+}
+
+func TestCompleteFromSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	snap := &oktaapi.Snapshot{
+		Groups:     []oktaapi.Group{{ID: "00g1emaKYZTWRYYRRTSK", Profile: oktaapi.Profile{Name: "Fake Group 01"}}},
+		GroupUsers: map[string][]oktaapi.User{},
+		AppGroups:  map[string][]oktaapi.GroupAssignmentResp{},
+		AppUsers:   map[string][]oktaapi.AppUser{},
+	}
+	if err := snap.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Cleanup(func() { snapshotDir = "" })
+	buf := &bytes.Buffer{}
+	rootCmd.SetOut(buf)
+	t.Cleanup(func() { rootCmd.SetOut(nil); rootCmd.SetArgs(nil) })
+	rootCmd.SetArgs([]string{"__complete", "--from-snapshot", dir, "list", "users", "Fa"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Fake Group 01\t00g1emaKYZTWRYYRRTSK\n:4\n"; !strings.HasPrefix(buf.String(), want) {
+		t.Errorf("completions:\n%s\nwant prefix:\n%s", buf, want)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(os.Getenv("HOME"), ".oktactl", "cache", "completion", "*.json")); len(matches) != 1 {
+		t.Errorf("cache files = %v, want 1", matches)
+	}
+}
+
+func TestCompleteIDsFromOneListing(t *testing.T) {
+	dir := t.TempDir()
+	snap := &oktaapi.Snapshot{
+		Groups: []oktaapi.Group{
+			{ID: "00g1emaKYZTWRYYRRTSK", Profile: oktaapi.Profile{Name: "Fake Group 01"}},
+			{ID: "00gg0xVALADWBPXOFZAS", Profile: oktaapi.Profile{Name: "Fake Group 02"}},
+		},
+		GroupUsers: map[string][]oktaapi.User{},
+		AppGroups:  map[string][]oktaapi.GroupAssignmentResp{},
+		AppUsers:   map[string][]oktaapi.AppUser{},
+	}
+	if err := snap.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Cleanup(func() { snapshotDir = "" })
+	t.Cleanup(func() { rootCmd.SetOut(nil); rootCmd.SetArgs(nil) })
+	for toComplete, want := range map[string]string{
+		"":     "00g1emaKYZTWRYYRRTSK\tFake Group 01\n00gg0xVALADWBPXOFZAS\tFake Group 02\n:4\n",
+		"00g":  "00g1emaKYZTWRYYRRTSK\tFake Group 01\n00gg0xVALADWBPXOFZAS\tFake Group 02\n:4\n",
+		"00gg": "00gg0xVALADWBPXOFZAS\tFake Group 02\n:4\n",
+	} {
+		buf := &bytes.Buffer{}
+		rootCmd.SetOut(buf)
+		rootCmd.SetArgs([]string{"__complete", "--from-snapshot", dir, "list", "users", toComplete})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(buf.String(), want) {
+			t.Errorf("completions of %q:\n%s\nwant prefix:\n%s", toComplete, buf, want)
+		}
+	}
+	if matches, _ := filepath.Glob(filepath.Join(os.Getenv("HOME"), ".oktactl", "cache", "completion", "*.json")); len(matches) != 1 {
+		t.Errorf("cache files = %v, want 1 for every ID prefix", matches)
+	}
+}
+
+func TestWriteCompletionScript(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		buf := &bytes.Buffer{}
+		if err := writeCompletionScript(buf, rootCmd, shell); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), "oktactl") {
+			t.Errorf("%s completion script does not mention oktactl", shell)
+		}
+	}
+	if err := writeCompletionScript(&bytes.Buffer{}, rootCmd, "powershell"); err == nil {
+		t.Error("expected error for unsupported shell")
+	}
+}
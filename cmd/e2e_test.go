@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/flynshue/oktactl/pkg/fakeokta"
+	"github.com/flynshue/oktactl/pkg/journal"
+	"github.com/flynshue/oktactl/pkg/okta-api/v2/oktaapi"
+	"github.com/okta/okta-sdk-golang/v2/okta"
+)
+
+func newFakeOrg(t *testing.T) (*oktaapi.OktaClient, *fakeokta.Server) {
+	t.Helper()
+	seed, err := fakeokta.LoadSeed("../pkg/fakeokta/testdata/seed.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := fakeokta.New(seed)
+	t.Cleanup(srv.Close)
+	// The tests read back what they change, which the SDK's response cache would hide.
+	client, err := oktaapi.NewClient(srv.URL, srv.Token, append(srv.Options(), okta.WithCache(false))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, srv
+}
+
+func sortedMembers(srv *fakeokta.Server, groupID string) string {
+	members := srv.Members(groupID)
+	sort.Strings(members)
+	return strings.Join(members, ",")
+}
+
+func TestE2EApplyMembership(t *testing.T) {
+	client, srv := newFakeOrg(t)
+	desired := &desiredGroups{Groups: []desiredGroup{
+		{ID: "00g1admins0000000001", Name: "Super Admins", Members: []string{"bob@example.com"}},
+		{ID: "00g1eng0000000000002", Name: "Engineering", Members: []string{"alex@example.com"}},
+	}}
+	changes, err := planMembership(client, desired, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := applyMembership(client, changes, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := sortedMembers(srv, "00g1admins0000000001"); got != "00u1bobb000000000002" {
+		t.Errorf("admins = %s", got)
+	}
+	// Engineering is managed by a group rule, so bob is not removed.
+	if got := sortedMembers(srv, "00g1eng0000000000002"); got != "00u1alex000000000001,00u1bobb000000000002" {
+		t.Errorf("engineering = %s", got)
+	}
+	if again, err := planMembership(client, desired, true); err != nil || len(again) != 1 || !again[0].Protected {
+		t.Errorf("plan after apply = %+v, %v", again, err)
+	}
+}
+
+func TestE2EOffboardRollback(t *testing.T) {
+	client, srv := newFakeOrg(t)
+	j := journal.Open(filepath.Join(t.TempDir(), "journal.jsonl"))
+	admin := newJournaledAdmin(client, j, "oktactl user offboard alex@example.com")
+	receipt, err := offboardUser(admin, "alex@example.com", offboardOptions{Suspend: true}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(receipt.Groups) != 3 || len(receipt.Apps) != 2 || len(receipt.RemovedGroups) != 1 || len(receipt.SkippedGroups) != 2 {
+		t.Errorf("receipt = %+v", receipt)
+	}
+	alex, _ := srv.User("alex@example.com")
+	if alex.Status != "SUSPENDED" || srv.Sessions(alex.ID) != 1 || sortedMembers(srv, "00g1admins0000000001") != "" {
+		t.Errorf("after offboarding: status %s, sessions %d, admins %q", alex.Status, srv.Sessions(alex.ID), sortedMembers(srv, "00g1admins0000000001"))
+	}
+
+	if err := rollback(client, j, admin.id, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	alex, _ = srv.User("alex@example.com")
+	if alex.Status != "ACTIVE" || sortedMembers(srv, "00g1admins0000000001") != alex.ID {
+		t.Errorf("after rollback: status %s, admins %q", alex.Status, sortedMembers(srv, "00g1admins0000000001"))
+	}
+}
+
+func TestE2EOffboardDeactivateRestore(t *testing.T) {
+	client, srv := newFakeOrg(t)
+	receipt, err := offboardUser(client, "bob@example.com", offboardOptions{Deactivate: true}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := restoreUser(client, receipt, false, buf); err != nil {
+		t.Fatal(err)
+	}
+	bob, _ := srv.User("bob@example.com")
+	if bob.Status != "PROVISIONED" {
+		t.Errorf("status after restore = %s, want PROVISIONED", bob.Status)
+	}
+	if !strings.Contains(buf.String(), "bob@example.com (00u1bobb000000000002) is PROVISIONED, not ACTIVE, until they set a password") {
+		t.Errorf("restore did not say the user still has to set a password:\n%s", buf)
+	}
+}
+
+func TestE2EGroupAddUserByName(t *testing.T) {
+	client, srv := newFakeOrg(t)
+	path := filepath.Join(t.TempDir(), "expirations.json")
+	if err := runGroupAddUser(&bytes.Buffer{}, client, path, "contractors", "bob@example.com", 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := sortedMembers(srv, "00g1contract00000003"); got != "00u1bobb000000000002,00u1cara000000000003" {
+		t.Errorf("Contractors members = %s", got)
+	}
+	buf := &bytes.Buffer{}
+	if err := listAppsGroups(buf, client, "aws"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "Group assignment for 0oa1aws0000000000001 AWS Prod\n") {
+		t.Errorf("list apps groups aws:\n%s", buf)
+	}
+}
+
+func TestTransportOptions(t *testing.T) {
+	defer func() { recordDir, replayDir = "", "" }()
+	recordDir, replayDir = t.TempDir(), t.TempDir()
+	if _, err := transportOptions(false); err == nil {
+		t.Error("expected error using --record and --replay together")
+	}
+	replayDir = ""
+	if opts, err := transportOptions(false); err != nil || len(opts) != 2 {
+		t.Errorf("--record options = %v, %v", opts, err)
+	}
+	recordDir, replayDir = "", t.TempDir()
+	if _, err := transportOptions(false); err == nil {
+		t.Error("expected error replaying an empty directory")
+	}
+}
+
+func TestE2EListStreams(t *testing.T) {
+	client, srv := newFakeOrg(t)
+	srv.MaxPageSize = 1
+	buf := &bytes.Buffer{}
+	if err := listOktaGroupUsers(context.Background(), buf, client, "Everyone"); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(buf.String(), "@example.com"); got != 3 {
+		t.Errorf("listed %d members across pages of one, want 3:\n%s", got, buf)
+	}
+	buf.Reset()
+	if err := listApps(context.Background(), buf, client, "nothing"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "no apps found using keyword nothing\nOkta App ID") {
+		t.Errorf("listApps with no matches:\n%s", buf)
+	}
+
+	// An interrupted list stops instead of fetching the remaining pages.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := listOktaGroupUsers(ctx, io.Discard, client, "00g0everyone00000000"); !errors.Is(err, context.Canceled) {
+		t.Errorf("listOktaGroupUsers with a canceled context = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/flynshue/oktactl/pkg/expiry"
+	"github.com/flynshue/oktactl/pkg/okta-api/v2/oktaapi"
+	"github.com/spf13/viper"
+)
+
+// addGroupUser adds the user to a group. With a positive expires the membership is recorded in
+// store so that `oktactl reconcile expirations` removes it later; without one any recorded expiry
+// is forgotten and the membership becomes permanent. A user who is already a permanent member
+// is not given an expiry, since reconciling it would revoke access they had before.
+func addGroupUser(admin OktaGroupAdmin, store *expiry.Store, groupID, ref string, expires time.Duration, now time.Time, w io.Writer) error {
+	if expires < 0 {
+		return fmt.Errorf("--expires must be positive")
+	}
+	user, err := oktaapi.ResolveUser(admin, ref)
+	if err != nil {
+		return fmt.Errorf("user %s: %w", ref, err)
+	}
+	groups, err := admin.ListOktaUserGroups(user.ID)
+	if err != nil {
+		return err
+	}
+	_, timeBound := store.Get(groupID, user.ID)
+	for _, group := range groups {
+		if group.ID == groupID && expires > 0 && !timeBound {
+			return fmt.Errorf("%s is already a permanent member of group %s", userLabel(user), groupID)
+		}
+	}
+	if err := admin.AddOktaGroupUser(groupID, user.ID); err != nil {
+		return fmt.Errorf("adding to group %s: %w", groupID, err)
+	}
+	if expires == 0 {
+		store.Remove(groupID, user.ID)
+		if err := store.Save(); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "added %s to group %s\n", userLabel(user), groupID)
+		return nil
+	}
+	m := expiry.Membership{GroupID: groupID, UserID: user.ID, User: userLabel(user), Added: now.UTC(), Expires: now.Add(expires).UTC()}
+	store.Put(m)
+	if err := store.Save(); err != nil {
+		return fmt.Errorf("added %s to group %s but could not record the expiry: %w", m.User, groupID, err)
+	}
+	fmt.Fprintf(w, "added %s to group %s until %s\n", m.User, groupID, m.Expires.Format(time.RFC3339))
+	return nil
+}
+
+// reconcileExpirations removes every membership in store that has expired at now. Memberships
+// that cannot be removed stay in the store so the next run retries them.
+func reconcileExpirations(admin OktaGroupAdmin, store *expiry.Store, now time.Time, logger *log.Logger) error {
+	due := store.Due(now)
+	failed := 0
+	for _, m := range due {
+		if err := admin.RemoveOktaGroupUser(m.GroupID, m.UserID); err != nil {
+			logger.Printf("failed to remove %s from group %s (expired %s): %s", m.User, m.GroupID, m.Expires.Format(time.RFC3339), err)
+			failed++
+			continue
+		}
+		store.Expire(m)
+		logger.Printf("removed %s from group %s (expired %s)", m.User, m.GroupID, m.Expires.Format(time.RFC3339))
+	}
+	if len(due) > failed {
+		if err := store.Save(); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d expired memberships could not be removed", failed, len(due))
+	}
+	return nil
+}
+
+// expirationsPath returns the file named by the expirations config key, or the default store.
+func expirationsPath() string {
+	path := viper.GetString("expirations")
+	if path == "" {
+		var err error
+		if path, err = expiry.DefaultPath(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	return path
+}
+
+func runGroupAddUser(w io.Writer, admin OktaGroupAdmin, path, groupRef, ref string, expires time.Duration) error {
+	groupID, err := oktaapi.ResolveGroupID(admin, groupRef)
+	if err != nil {
+		return err
+	}
+	store, err := expiry.Load(path)
+	if err != nil {
+		return err
+	}
+	return addGroupUser(admin, store, groupID, ref, expires, time.Now(), w)
+}
+
+// runReconcileExpirations reconciles once, or every interval until interrupted when loop is set.
+// Each pass reloads the store and gets its own admin so that its removals are journaled as one operation.
+func runReconcileExpirations(w io.Writer, newPassAdmin func() OktaGroupAdmin, path string, loop bool, interval time.Duration) error {
+	logger := log.New(w, "", log.LstdFlags)
+	pass := func() error {
+		store, err := expiry.Load(path)
+		if err != nil {
+			return err
+		}
+		return reconcileExpirations(newPassAdmin(), store, time.Now(), logger)
+	}
+	if !loop {
+		return pass()
+	}
+	if interval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := pass(); err != nil {
+			logger.Print(err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
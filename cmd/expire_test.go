@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"bytes"
+	"log"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flynshue/oktactl/pkg/expiry"
+)
+
+func TestAddGroupUserExpires(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "expirations.json")
+	store, err := expiry.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	client := &MockOktaClient{}
+	buf := &bytes.Buffer{}
+	if err := addGroupUser(client, store, "00gg0xVALADWBPXOFZAS", "target@example.com", 72*time.Hour, now, buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(client.added, ","); got != "00gg0xVALADWBPXOFZAS/00utarget" {
+		t.Errorf("added = %s", got)
+	}
+	if !strings.Contains(buf.String(), "until 2024-03-04T12:00:00Z") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+	if err := addGroupUser(client, store, "00g1emaKYZTWRYYRRTSK", "target@example.com", time.Hour, now, buf); err == nil {
+		t.Error("expected error adding an expiry to a permanent membership")
+	}
+
+	// An existing time-bound membership can be extended.
+	store.Put(expiry.Membership{GroupID: "00g1emaKYZTWRYYRRTSK", UserID: "00utarget", Expires: now.Add(time.Hour)})
+	if err := addGroupUser(client, store, "00g1emaKYZTWRYYRRTSK", "target@example.com", 24*time.Hour, now, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := expiry.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Memberships) != 2 {
+		t.Fatalf("saved %d memberships, want 2", len(saved.Memberships))
+	}
+	if m, _ := saved.Get("00g1emaKYZTWRYYRRTSK", "00utarget"); !m.Expires.Equal(now.Add(24 * time.Hour)) {
+		t.Errorf("extended expiry = %s", m.Expires)
+	}
+}
+
+func TestReconcileExpirations(t *testing.T) {
+	store, err := expiry.Load(filepath.Join(t.TempDir(), "expirations.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	store.Put(expiry.Membership{GroupID: "00gg0xVALADWBPXOFZAS", UserID: "00utarget", User: "target@example.com", Expires: now.Add(-time.Minute)})
+	store.Put(expiry.Membership{GroupID: "00gg0xVALADWBPXOFZAK", UserID: "00utarget", User: "target@example.com", Expires: now.Add(time.Hour)})
+	client := &MockOktaClient{}
+	buf := &bytes.Buffer{}
+	if err := reconcileExpirations(client, store, now, log.New(buf, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(client.removed, ","); got != "00gg0xVALADWBPXOFZAS/00utarget" {
+		t.Errorf("removed = %s", got)
+	}
+	if got := buf.String(); got != "removed target@example.com from group 00gg0xVALADWBPXOFZAS (expired 2024-03-01T11:59:00Z)\n" {
+		t.Errorf("unexpected log: %q", got)
+	}
+	if len(store.Memberships) != 1 || store.Memberships[0].GroupID != "00gg0xVALADWBPXOFZAK" {
+		t.Errorf("remaining memberships = %+v", store.Memberships)
+	}
+}
+
+func TestExpirationsFromSnapshot(t *testing.T) {
+	assertRequiresLiveOrg(t, "group", "add-user", "00gg0xVALADWBPXOFZAS", "target@example.com")
+	assertRequiresLiveOrg(t, "reconcile", "expirations")
+}
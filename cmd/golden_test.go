@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flynshue/oktactl/pkg/expiry"
+	"github.com/flynshue/oktactl/pkg/journal"
+	"github.com/flynshue/oktactl/pkg/watch"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata/golden")
+
+// assertGolden compares got with testdata/golden/<name>.golden, rewriting the file instead when
+// the tests are run with -update.
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test ./cmd -run Golden -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s:\n--- got\n%s\n--- want\n%s", path, got, want)
+	}
+}
+
+func writeTestFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const goldenRules = `
+rules:
+  - name: fake groups only
+    app: 0oa1gjh63g214q0Hq0g4
+    allowedGroups: "^Fake Group 0[12]$"
+  - name: small group
+    group: 00g1emaKYZTWRYYRRTSK
+    maxMembers: 2
+`
+
+const goldenSod = `
+conflicts:
+  - name: approver and app
+    a:
+      group: 00gbkkGFFWZDLCNTAGQR
+    b:
+      app: 0oa1gjh63g214q0Hq0g4
+`
+
+const goldenGroups = `
+groups:
+  - id: 00g1emaKYZTWRYYRRTSK
+    name: Fake Group 01
+    members:
+      - user0@example.com
+      - 00gg0xVALADWBPXOFZAS
+      - new@example.com
+`
+
+func TestGolden(t *testing.T) {
+	rules := writeTestFile(t, "rules.yaml", goldenRules)
+	sod := writeTestFile(t, "sod.yaml", goldenSod)
+	groups := writeTestFile(t, "groups.yaml", goldenGroups)
+	started := time.Date(2024, 3, 1, 10, 15, 0, 0, time.UTC)
+	events := []watch.Event{
+		{Time: started, Resource: watch.Group, ResourceID: "00g1emaKYZTWRYYRRTSK", Action: watch.Added, MemberID: "00u1hqieohhlPBv581d8", MemberName: "direct@example.com"},
+		{Time: started.Add(time.Minute), Resource: watch.Group, ResourceID: "00g1emaKYZTWRYYRRTSK", Action: watch.Removed, MemberID: "00gg0xVALADWBPXOFZAK", MemberName: "user2@example.com"},
+	}
+	writeEvents := func(w io.Writer, format string) error {
+		emit, err := eventWriter(w, format)
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			if err := emit(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name string
+		run  func(w io.Writer) error
+		// err is the error the command returns after writing its output, if any.
+		err string
+	}{
+		{name: "list-apps", run: func(w io.Writer) error { return listApps(context.Background(), w, &MockOktaClient{}, "test") }},
+		{name: "list-app", run: func(w io.Writer) error { return getAppById(w, &MockOktaClient{}, "0oa1gjh63g214q0Hq0g4") }},
+		{name: "list-apps-groups", run: func(w io.Writer) error { return listAppsGroups(w, &MockOktaClient{}, "0oa1gjh63g214q0Hq0g4") }},
+		{name: "list-groups", run: func(w io.Writer) error { return listOktaGroups(context.Background(), w, &MockOktaClient{}, "fake") }},
+		{name: "list-group-users", run: func(w io.Writer) error {
+			return listOktaGroupUsers(context.Background(), w, &MockOktaClient{}, "00g1emaKYZTWRYYRRTSK")
+		}},
+		{name: "audit-hygiene-table", run: func(w io.Writer) error { return runAuditHygiene(w, &MockOktaClient{}, "table") }},
+		{name: "audit-hygiene-json", run: func(w io.Writer) error { return runAuditHygiene(w, &MockOktaClient{}, "json") }},
+		{name: "audit-stale-users-table", run: func(w io.Writer) error { return runAuditStaleUsers(w, &MockOktaClient{}, 90, "table") }},
+		{name: "audit-stale-users-json", run: func(w io.Writer) error { return runAuditStaleUsers(w, &MockOktaClient{}, 90, "json") }},
+		{name: "audit-check-table", run: func(w io.Writer) error { return runAuditCheck(w, &MockOktaClient{}, rules, "table") }, err: "2 rule violations"},
+		{name: "audit-check-json", run: func(w io.Writer) error { return runAuditCheck(w, &MockOktaClient{}, rules, "json") }, err: "2 rule violations"},
+		{name: "audit-sod-table", run: func(w io.Writer) error { return runAuditSod(w, io.Discard, &MockOktaClient{}, sod, "table") }, err: "3 separation of duties conflicts"},
+		{name: "audit-sod-json", run: func(w io.Writer) error { return runAuditSod(w, io.Discard, &MockOktaClient{}, sod, "json") }, err: "3 separation of duties conflicts"},
+		{name: "report-app-access-csv", run: func(w io.Writer) error {
+			return reportAppAccess(w, io.Discard, &MockOktaClient{}, "0oa1gjh63g214q0Hq0g4", "csv")
+		}},
+		{name: "report-app-access-markdown", run: func(w io.Writer) error {
+			return reportAppAccess(w, io.Discard, &MockOktaClient{}, "0oa1gjh63g214q0Hq0g4", "markdown")
+		}},
+		{name: "report-app-access-html", run: func(w io.Writer) error {
+			return reportAppAccess(w, io.Discard, &MockOktaClient{}, "0oa1gjh63g214q0Hq0g4", "html")
+		}},
+		{name: "plan", run: func(w io.Writer) error { return runPlan(w, &MockOktaClient{}, groups, true) }},
+		{name: "apply", run: func(w io.Writer) error {
+			return runApply(strings.NewReader("yes\n"), w, &MockOktaClient{}, groups, true, false)
+		}},
+		{name: "user-mirror", run: func(w io.Writer) error {
+			return runUserMirror(strings.NewReader("yes\n"), w, &MockOktaClient{}, "source@example.com", "target@example.com", false)
+		}},
+		{name: "user-offboard-dry-run", run: func(w io.Writer) error {
+			_, err := offboardUser(&MockOktaClient{}, "source@example.com", offboardOptions{Suspend: true, DryRun: true}, w)
+			return err
+		}},
+		{name: "user-offboard", run: func(w io.Writer) error {
+			_, err := offboardUser(&MockOktaClient{}, "source@example.com", offboardOptions{Deactivate: true}, w)
+			return err
+		}},
+		{name: "user-restore", run: func(w io.Writer) error {
+			receipt, err := offboardUser(&MockOktaClient{}, "source@example.com", offboardOptions{Deactivate: true}, io.Discard)
+			if err != nil {
+				return err
+			}
+			return restoreUser(&MockOktaClient{}, receipt, false, w)
+		}},
+		{name: "journal-rollback", run: func(w io.Writer) error {
+			j := journal.Open(filepath.Join(t.TempDir(), "journal.jsonl"))
+			admin := newJournaledAdmin(&MockOktaClient{}, j, "oktactl user offboard source@example.com")
+			admin.id = "20240301T101500-a1b2c3"
+			if _, err := offboardUser(admin, "source@example.com", offboardOptions{Suspend: true}, io.Discard); err != nil {
+				return err
+			}
+			buf := &bytes.Buffer{}
+			if err := rollback(&MockOktaClient{}, j, admin.id, buf); err != nil {
+				return err
+			}
+			// The rollback's own operation ID is random.
+			ops, err := j.Operations()
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(w, strings.ReplaceAll(buf.String(), ops[0].RolledBackBy, "20240301T102000-d4e5f6"))
+			return err
+		}},
+		{name: "group-add-user", run: func(w io.Writer) error {
+			store, err := expiry.Load(filepath.Join(t.TempDir(), "expirations.json"))
+			if err != nil {
+				return err
+			}
+			if err := addGroupUser(&MockOktaClient{}, store, "00gg0xVALADWBPXOFZAS", "target@example.com", 72*time.Hour, started, w); err != nil {
+				return err
+			}
+			return addGroupUser(&MockOktaClient{}, store, "00gg0xVALADWBPXOFZAS", "source@example.com", 0, started, w)
+		}},
+		{name: "reconcile-expirations", run: func(w io.Writer) error {
+			store, err := expiry.Load(filepath.Join(t.TempDir(), "expirations.json"))
+			if err != nil {
+				return err
+			}
+			store.Put(expiry.Membership{GroupID: "00gg0xVALADWBPXOFZAS", UserID: "00utarget", User: "target@example.com", Expires: started.Add(-time.Minute)})
+			store.Put(expiry.Membership{GroupID: "00g1emaKYZTWRYYRRTSK", UserID: "00usource", User: "source@example.com", Expires: started.Add(time.Hour)})
+			return reconcileExpirations(&MockOktaClient{}, store, started, log.New(w, "", 0))
+		}},
+		{name: "export-snapshot", run: func(w io.Writer) error {
+			client, _ := newFakeOrg(t)
+			dir := filepath.Join(t.TempDir(), "snapshot")
+			buf := &bytes.Buffer{}
+			if err := exportSnapshot(buf, client, dir); err != nil {
+				return err
+			}
+			_, err := io.WriteString(w, strings.ReplaceAll(buf.String(), dir, "snapshot"))
+			return err
+		}},
+		{name: "compare-users", run: func(w io.Writer) error {
+			return runCompareUsers(w, &MockOktaClient{}, "source@example.com", "target@example.com")
+		}},
+		{name: "compare-groups", run: func(w io.Writer) error {
+			return runCompareGroups(w, &MockOktaClient{}, "00g1emaKYZTWRYYRRTSK", "00gg0xVALADWBPXOFZAS")
+		}},
+		{name: "export-terraform", run: func(w io.Writer) error { return runExportTerraform(w, &MockOktaClient{}, "Fake*", "") }},
+		{name: "journal-list", run: func(w io.Writer) error {
+			return writeJournalOperations(w, []journal.Operation{
+				{ID: "20240301T101500-a1b2c3", Started: started, Command: "oktactl apply", Changes: 2, RolledBackBy: "20240301T102000-d4e5f6"},
+				{ID: "20240301T102000-d4e5f6", Started: started.Add(5 * time.Minute), Command: "oktactl journal rollback 20240301T101500-a1b2c3", RollbackOf: "20240301T101500-a1b2c3", Changes: 2},
+			})
+		}},
+		{name: "watch-table", run: func(w io.Writer) error { return writeEvents(w, "table") }},
+		{name: "watch-ndjson", run: func(w io.Writer) error { return writeEvents(w, "ndjson") }},
+		{name: "version", run: func(w io.Writer) error {
+			versionCmd.SetOut(w)
+			defer versionCmd.SetOut(nil)
+			versionCmd.Run(versionCmd, nil)
+			return nil
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			err := tt.run(buf)
+			if (err == nil && tt.err != "") || (err != nil && err.Error() != tt.err) {
+				t.Fatalf("error = %v, want %q", err, tt.err)
+			}
+			assertGolden(t, tt.name, buf.Bytes())
+		})
+	}
+}
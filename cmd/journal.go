@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"text/tabwriter"
+	"time"
+
+	"github.com/flynshue/oktactl/pkg/journal"
+	"github.com/spf13/viper"
+)
+
+// journaledAdmin records every successful write made through it in the journal.
+// All writes share one operation ID so the command run can be rolled back as a whole.
+type journaledAdmin struct {
+	OktaUserAdmin
+	journal    *journal.Journal
+	id         string
+	command    string
+	rollbackOf string
+}
+
+func newJournaledAdmin(admin OktaUserAdmin, j *journal.Journal, command string) *journaledAdmin {
+	return &journaledAdmin{OktaUserAdmin: admin, journal: j, id: journal.NewID(), command: command}
+}
+
+// record runs write and appends op to the journal if it succeeds.
+func (ja *journaledAdmin) record(op journal.Op, write func() error) error {
+	if err := write(); err != nil {
+		return err
+	}
+	entry := journal.Entry{ID: ja.id, Time: time.Now().UTC(), Command: ja.command, RollbackOf: ja.rollbackOf, Op: op, Inverse: op.Inverse()}
+	if err := ja.journal.Append(entry); err != nil {
+		return fmt.Errorf("%s succeeded but could not be journaled: %w", op, err)
+	}
+	return nil
+}
+
+func (ja *journaledAdmin) AddOktaGroupUser(groupID, userID string) error {
+	return ja.record(journal.Op{Type: journal.AddGroupUser, GroupID: groupID, UserID: userID}, func() error {
+		return ja.OktaUserAdmin.AddOktaGroupUser(groupID, userID)
+	})
+}
+
+func (ja *journaledAdmin) RemoveOktaGroupUser(groupID, userID string) error {
+	return ja.record(journal.Op{Type: journal.RemoveGroupUser, GroupID: groupID, UserID: userID}, func() error {
+		return ja.OktaUserAdmin.RemoveOktaGroupUser(groupID, userID)
+	})
+}
+
+func (ja *journaledAdmin) ClearOktaUserSessions(userID string) error {
+	return ja.record(journal.Op{Type: journal.ClearSessions, UserID: userID}, func() error {
+		return ja.OktaUserAdmin.ClearOktaUserSessions(userID)
+	})
+}
+
+func (ja *journaledAdmin) SuspendOktaUser(userID string) error {
+	return ja.record(journal.Op{Type: journal.SuspendUser, UserID: userID}, func() error {
+		return ja.OktaUserAdmin.SuspendOktaUser(userID)
+	})
+}
+
+func (ja *journaledAdmin) UnsuspendOktaUser(userID string) error {
+	return ja.record(journal.Op{Type: journal.UnsuspendUser, UserID: userID}, func() error {
+		return ja.OktaUserAdmin.UnsuspendOktaUser(userID)
+	})
+}
+
+func (ja *journaledAdmin) DeactivateOktaUser(userID string) error {
+	return ja.record(journal.Op{Type: journal.DeactivateUser, UserID: userID}, func() error {
+		return ja.OktaUserAdmin.DeactivateOktaUser(userID)
+	})
+}
+
+func (ja *journaledAdmin) ActivateOktaUser(userID string) error {
+	return ja.record(journal.Op{Type: journal.ActivateUser, UserID: userID}, func() error {
+		return ja.OktaUserAdmin.ActivateOktaUser(userID)
+	})
+}
+
+// applyOp makes the change described by op.
+func applyOp(admin OktaUserAdmin, op journal.Op) error {
+	switch op.Type {
+	case journal.AddGroupUser:
+		return admin.AddOktaGroupUser(op.GroupID, op.UserID)
+	case journal.RemoveGroupUser:
+		return admin.RemoveOktaGroupUser(op.GroupID, op.UserID)
+	case journal.ClearSessions:
+		return admin.ClearOktaUserSessions(op.UserID)
+	case journal.SuspendUser:
+		return admin.SuspendOktaUser(op.UserID)
+	case journal.UnsuspendUser:
+		return admin.UnsuspendOktaUser(op.UserID)
+	case journal.DeactivateUser:
+		return admin.DeactivateOktaUser(op.UserID)
+	case journal.ActivateUser:
+		return admin.ActivateOktaUser(op.UserID)
+	default:
+		return fmt.Errorf("unknown journal operation %q", op.Type)
+	}
+}
+
+// rollback applies the inverse of every change in operation id, newest first.
+// Changes that cannot be undone, such as clearing sessions, are skipped. A rollback that
+// stopped partway is resumed under its own operation ID, skipping the changes it already
+// undid, and is only marked done once every change has been undone.
+func rollback(admin OktaUserAdmin, j *journal.Journal, id string, w io.Writer) error {
+	ops, err := j.Operations()
+	if err != nil {
+		return err
+	}
+	resume := ""
+	for _, op := range ops {
+		if op.ID != id {
+			continue
+		}
+		if op.RolledBackBy != "" {
+			return fmt.Errorf("operation %s was already rolled back by %s", id, op.RolledBackBy)
+		}
+		resume = op.IncompleteRollbackBy
+	}
+	entries, err := j.Operation(id)
+	if err != nil {
+		return err
+	}
+	ja := newJournaledAdmin(admin, j, "oktactl journal rollback "+id)
+	ja.rollbackOf = id
+	undoneBefore := map[journal.Op]int{}
+	if resume != "" {
+		prior, err := j.Operation(resume)
+		if err != nil {
+			return err
+		}
+		for _, e := range prior {
+			if !e.Done {
+				undoneBefore[e.Op]++
+			}
+		}
+		ja.id = resume
+		fmt.Fprintf(w, "resuming rollback %s\n", resume)
+	}
+	undone := 0
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.Done {
+			continue
+		}
+		if e.Inverse == nil {
+			fmt.Fprintf(w, "skipping %s: cannot be undone\n", e.Op)
+			continue
+		}
+		if undoneBefore[*e.Inverse] > 0 {
+			undoneBefore[*e.Inverse]--
+			fmt.Fprintf(w, "skipping %s: already undone\n", e.Op)
+			continue
+		}
+		if err := applyOp(ja, *e.Inverse); err != nil {
+			return fmt.Errorf("undoing %s: %w (run the rollback again to resume it)", e.Op, err)
+		}
+		undone++
+		fmt.Fprintf(w, "undid %s\n", e.Op)
+	}
+	if err := j.Append(journal.Entry{ID: ja.id, Time: time.Now().UTC(), Command: ja.command, RollbackOf: id, Done: true}); err != nil {
+		return fmt.Errorf("every change was undone but the rollback could not be marked done: %w", err)
+	}
+	fmt.Fprintf(w, "rolled back %d changes from %s as operation %s\n", undone, id, ja.id)
+	return nil
+}
+
+func writeJournalOperations(w io.Writer, ops []journal.Operation) error {
+	if len(ops) == 0 {
+		fmt.Fprintln(w, "journal is empty")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.TabIndent)
+	fmt.Fprintln(tw, "Operation ID\t Started\t Changes\t Command\t Rolled Back By\t")
+	for _, op := range ops {
+		rolledBackBy := op.RolledBackBy
+		if op.IncompleteRollbackBy != "" {
+			rolledBackBy = op.IncompleteRollbackBy + " (incomplete)"
+		}
+		fmt.Fprintf(tw, "%s\t %s\t %d\t %s\t %s\t\n", op.ID, op.Started.Format(time.RFC3339), op.Changes, op.Command, rolledBackBy)
+	}
+	return tw.Flush()
+}
+
+// openJournal returns the journal named by the journal config key, or the default journal.
+func openJournal() *journal.Journal {
+	path := viper.GetString("journal")
+	if path == "" {
+		var err error
+		if path, err = journal.DefaultPath(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	return journal.Open(path)
+}
+
+// newAdmin returns the uncached live client wrapped so that every write made by command is
+// journaled.
+func newAdmin(command string) OktaUserAdmin {
+	return newJournaledAdmin(newUncachedClient(), openJournal(), command)
+}
+
+func runJournalList(w io.Writer, j *journal.Journal) error {
+	ops, err := j.Operations()
+	if err != nil {
+		return err
+	}
+	return writeJournalOperations(w, ops)
+}
+
+func runJournalRollback(w io.Writer, admin OktaUserAdmin, j *journal.Journal, id string) error {
+	return rollback(admin, j, id, w)
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flynshue/oktactl/pkg/journal"
+)
+
+func TestJournaledOffboardRollback(t *testing.T) {
+	j := journal.Open(filepath.Join(t.TempDir(), "journal.jsonl"))
+	client := &MockOktaClient{}
+	admin := newJournaledAdmin(client, j, "oktactl user offboard source@example.com")
+	if _, err := offboardUser(admin, "source@example.com", offboardOptions{Suspend: true}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := j.Operation(admin.id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("journaled %d changes, want 4", len(entries))
+	}
+
+	undo := &MockOktaClient{}
+	buf := &bytes.Buffer{}
+	if err := rollback(undo, j, admin.id, buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(undo.calls, ","); got != "unsuspend/00usource" {
+		t.Errorf("rollback calls = %s", got)
+	}
+	if got := strings.Join(undo.added, ","); got != "00gg0xVALADWBPXOFZAK/00usource,00g1emaKYZTWRYYRRTSK/00usource" {
+		t.Errorf("rollback added = %s", got)
+	}
+	if !strings.Contains(buf.String(), "skipping user.sessions.clear user=00usource: cannot be undone") {
+		t.Errorf("unexpected rollback output:\n%s", buf.String())
+	}
+	ops, err := j.Operations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 2 || ops[0].RolledBackBy != ops[1].ID {
+		t.Errorf("operations = %+v", ops)
+	}
+	if err := writeJournalOperations(&bytes.Buffer{}, ops); err != nil {
+		t.Error(err)
+	}
+	if err := rollback(undo, j, admin.id, &bytes.Buffer{}); err == nil {
+		t.Error("expected error rolling back an operation twice")
+	}
+}
+
+// flakyAdmin fails the add-th group addition once.
+type flakyAdmin struct {
+	*MockOktaClient
+	adds, failOn int
+}
+
+func (f *flakyAdmin) AddOktaGroupUser(groupID, userID string) error {
+	f.adds++
+	if f.adds == f.failOn {
+		return errors.New("rate limited")
+	}
+	return f.MockOktaClient.AddOktaGroupUser(groupID, userID)
+}
+
+func TestRollbackResumes(t *testing.T) {
+	j := journal.Open(filepath.Join(t.TempDir(), "journal.jsonl"))
+	admin := newJournaledAdmin(&MockOktaClient{}, j, "oktactl user offboard source@example.com")
+	if _, err := offboardUser(admin, "source@example.com", offboardOptions{Suspend: true}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Newest first, the rollback unsuspends the user, skips clearing sessions, then adds
+	// the user back to two groups, the second of which fails.
+	undo := &flakyAdmin{MockOktaClient: &MockOktaClient{}, failOn: 2}
+	if err := rollback(undo, j, admin.id, &bytes.Buffer{}); err == nil {
+		t.Fatal("expected the rollback to fail")
+	}
+	ops, err := j.Operations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ops[0].RolledBackBy != "" || ops[0].IncompleteRollbackBy == "" {
+		t.Fatalf("operation after a failed rollback = %+v", ops[0])
+	}
+	partial := ops[0].IncompleteRollbackBy
+
+	buf := &bytes.Buffer{}
+	if err := rollback(undo, j, admin.id, buf); err != nil {
+		t.Fatalf("resuming the rollback: %s", err)
+	}
+	if got := strings.Join(undo.calls, ","); got != "unsuspend/00usource" {
+		t.Errorf("rollback calls = %s, want the user unsuspended once", got)
+	}
+	if got := strings.Join(undo.added, ","); got != "00gg0xVALADWBPXOFZAK/00usource,00g1emaKYZTWRYYRRTSK/00usource" {
+		t.Errorf("rollback added = %s, want each group once", got)
+	}
+	if !strings.Contains(buf.String(), "resuming rollback "+partial) || !strings.Contains(buf.String(), "already undone") {
+		t.Errorf("unexpected output resuming the rollback:\n%s", buf)
+	}
+	ops, err = j.Operations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 2 || ops[0].RolledBackBy != partial || ops[1].Changes != 3 {
+		t.Errorf("operations = %+v", ops)
+	}
+	if err := rollback(undo, j, admin.id, &bytes.Buffer{}); err == nil {
+		t.Error("expected error rolling back a finished rollback again")
+	}
+}
+
+func TestJournalRollbackFromSnapshot(t *testing.T) {
+	assertRequiresLiveOrg(t, "journal", "rollback", "20240301T101500-a1b2c3")
+}
@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/flynshue/oktactl/pkg/okta-api/v2/oktaapi"
+	"gopkg.in/yaml.v3"
+)
+
+// desiredGroups is the membership file read by `oktactl plan` and `oktactl apply`.
+type desiredGroups struct {
+	Groups []desiredGroup `yaml:"groups"`
+}
+
+// desiredGroup lists every member a managed group should have by user ID, login or email.
+type desiredGroup struct {
+	ID      string   `yaml:"id"`
+	Name    string   `yaml:"name,omitempty"`
+	Members []string `yaml:"members"`
+}
+
+func (g desiredGroup) label() string {
+	if g.Name == "" {
+		return g.ID
+	}
+	return fmt.Sprintf("%s (%s)", g.Name, g.ID)
+}
+
+const (
+	changeAdd    = "add"
+	changeRemove = "remove"
+)
+
+// membershipChange adds or removes one user from a group. Protected removals are planned but never applied.
+type membershipChange struct {
+	Action    string       `json:"action"`
+	GroupID   string       `json:"groupId"`
+	GroupName string       `json:"groupName,omitempty"`
+	User      oktaapi.User `json:"user"`
+	Protected bool         `json:"protected,omitempty"`
+}
+
+func loadDesiredGroups(path string) (*desiredGroups, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	desired := &desiredGroups{}
+	if err := yaml.Unmarshal(b, desired); err != nil {
+		return nil, fmt.Errorf("reading groups %s: %w", path, err)
+	}
+	seen := map[string]bool{}
+	for i, g := range desired.Groups {
+		if g.ID == "" {
+			return nil, fmt.Errorf("group %d %q: missing id", i+1, g.Name)
+		}
+		// A group without a members list would plan to remove everyone in it, so an empty
+		// group has to be asked for explicitly.
+		if g.Members == nil {
+			return nil, fmt.Errorf("group %s: missing members, write members: [] for a group that should have none", g.label())
+		}
+		if seen[g.ID] {
+			return nil, fmt.Errorf("group %s is listed more than once", g.label())
+		}
+		seen[g.ID] = true
+	}
+	return desired, nil
+}
+
+// ruleManagedGroups returns the IDs of groups that an active group rule adds members to.
+func ruleManagedGroups(admin OktaGroupAdmin) (map[string]bool, error) {
+	rules, err := admin.ListOktaGroupRules()
+	if err != nil {
+		return nil, err
+	}
+	managed := map[string]bool{}
+	for _, rule := range rules {
+		if rule.Status != "ACTIVE" {
+			continue
+		}
+		for _, id := range rule.Actions.AssignUserToGroups.GroupIDs {
+			managed[id] = true
+		}
+	}
+	return managed, nil
+}
+
+// userMatches reports whether ref is the user's ID, login or email.
+func userMatches(user oktaapi.User, ref string) bool {
+	return user.ID == ref || strings.EqualFold(user.Login, ref) || strings.EqualFold(user.Email, ref)
+}
+
+func userLabel(user oktaapi.User) string {
+	name := user.Login
+	if name == "" {
+		name = user.Email
+	}
+	if name == "" {
+		return user.ID
+	}
+	return fmt.Sprintf("%s (%s)", name, user.ID)
+}
+
+// planMembership compares the desired members of each group with its current members.
+// When protectRules is set, removals from groups managed by a group rule are marked protected,
+// whether or not the rule added the member, since the API does not say how a member was added.
+func planMembership(admin OktaGroupAdmin, desired *desiredGroups, protectRules bool) ([]membershipChange, error) {
+	managed := map[string]bool{}
+	if protectRules {
+		var err error
+		if managed, err = ruleManagedGroups(admin); err != nil {
+			return nil, err
+		}
+	}
+	changes := []membershipChange{}
+	for _, group := range desired.Groups {
+		current, err := admin.ListOktaGroupUsers(group.ID)
+		if err != nil {
+			return nil, fmt.Errorf("group %s: %w", group.label(), err)
+		}
+		isMember := map[string]bool{}
+		for _, user := range current {
+			isMember[user.ID] = true
+		}
+		for _, ref := range group.Members {
+			found := false
+			for _, user := range current {
+				if userMatches(user, ref) {
+					found = true
+					break
+				}
+			}
+			if found {
+				continue
+			}
+			user, err := oktaapi.ResolveUser(admin, ref)
+			if err != nil {
+				return nil, fmt.Errorf("group %s: member %s: %w", group.label(), ref, err)
+			}
+			if isMember[user.ID] {
+				continue
+			}
+			isMember[user.ID] = true
+			changes = append(changes, membershipChange{Action: changeAdd, GroupID: group.ID, GroupName: group.Name, User: user})
+		}
+		for _, user := range current {
+			wanted := false
+			for _, ref := range group.Members {
+				if userMatches(user, ref) {
+					wanted = true
+					break
+				}
+			}
+			if !wanted {
+				changes = append(changes, membershipChange{Action: changeRemove, GroupID: group.ID, GroupName: group.Name, User: user, Protected: managed[group.ID]})
+			}
+		}
+	}
+	return changes, nil
+}
+
+// writePlan prints changes grouped by group in the style of a Terraform plan.
+func writePlan(w io.Writer, changes []membershipChange) {
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "No changes. Group membership matches the configuration.")
+		return
+	}
+	fmt.Fprintln(w, "oktactl will perform the following actions:")
+	adds, removes, protected := 0, 0, 0
+	lastGroup := ""
+	for _, c := range changes {
+		if c.GroupID != lastGroup {
+			fmt.Fprintf(w, "\n  # group %s\n", desiredGroup{ID: c.GroupID, Name: c.GroupName}.label())
+			lastGroup = c.GroupID
+		}
+		switch {
+		case c.Action == changeAdd:
+			adds++
+			fmt.Fprintf(w, "  + %s\n", userLabel(c.User))
+		case c.Protected:
+			protected++
+			fmt.Fprintf(w, "  ~ %s kept, group is managed by a group rule\n", userLabel(c.User))
+		default:
+			removes++
+			fmt.Fprintf(w, "  - %s\n", userLabel(c.User))
+		}
+	}
+	fmt.Fprintf(w, "\nPlan: %d to add, %d to remove", adds, removes)
+	if protected > 0 {
+		fmt.Fprintf(w, ", %d protected", protected)
+	}
+	fmt.Fprintln(w, ".")
+}
+
+// applyMembership carries out every unprotected change, stopping at the first error.
+func applyMembership(admin OktaGroupAdmin, changes []membershipChange, w io.Writer) error {
+	added, removed := 0, 0
+	for _, c := range changes {
+		switch {
+		case c.Protected:
+			continue
+		case c.Action == changeAdd:
+			if err := admin.AddOktaGroupUser(c.GroupID, c.User.ID); err != nil {
+				return fmt.Errorf("adding %s to group %s: %w", userLabel(c.User), c.GroupID, err)
+			}
+			added++
+			fmt.Fprintf(w, "added %s to group %s\n", userLabel(c.User), c.GroupID)
+		default:
+			if err := admin.RemoveOktaGroupUser(c.GroupID, c.User.ID); err != nil {
+				return fmt.Errorf("removing %s from group %s: %w", userLabel(c.User), c.GroupID, err)
+			}
+			removed++
+			fmt.Fprintf(w, "removed %s from group %s\n", userLabel(c.User), c.GroupID)
+		}
+	}
+	fmt.Fprintf(w, "\nApply complete! %d added, %d removed.\n", added, removed)
+	return nil
+}
+
+// confirm asks the user to type yes before continuing.
+func confirm(r io.Reader, w io.Writer, prompt string) bool {
+	fmt.Fprintf(w, "\n%s\n  Only 'yes' will be accepted to approve.\n\n  Enter a value: ", prompt)
+	answer, _ := bufio.NewReader(r).ReadString('\n')
+	return strings.TrimSpace(answer) == "yes"
+}
+
+func runPlan(w io.Writer, admin OktaGroupAdmin, path string, protectRules bool) error {
+	desired, err := loadDesiredGroups(path)
+	if err != nil {
+		return err
+	}
+	changes, err := planMembership(admin, desired, protectRules)
+	if err != nil {
+		return err
+	}
+	writePlan(w, changes)
+	return nil
+}
+
+func runApply(r io.Reader, w io.Writer, admin OktaGroupAdmin, path string, protectRules, autoApprove bool) error {
+	desired, err := loadDesiredGroups(path)
+	if err != nil {
+		return err
+	}
+	changes, err := planMembership(admin, desired, protectRules)
+	if err != nil {
+		return err
+	}
+	writePlan(w, changes)
+	pending := 0
+	for _, c := range changes {
+		if !c.Protected {
+			pending++
+		}
+	}
+	if pending == 0 {
+		return nil
+	}
+	if !autoApprove && !confirm(r, w, "Do you want to perform these actions?") {
+		return fmt.Errorf("apply cancelled")
+	}
+	return applyMembership(admin, changes, w)
+}
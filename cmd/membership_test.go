@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlanApplyMembership(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "groups.yaml")
+	groups := `
+groups:
+  - id: 00g1emaKYZTWRYYRRTSK
+    name: Fake Group 01
+    members:
+      - user0@example.com
+      - 00gg0xVALADWBPXOFZAS
+      - new@example.com
+  - id: 00gg0xVALADWBPXOFZAS
+    members:
+      - user0@example.com
+`
+	if err := os.WriteFile(path, []byte(groups), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	desired, err := loadDesiredGroups(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &MockOktaClient{}
+	changes, err := planMembership(client, desired, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	writePlan(buf, changes)
+	for _, want := range []string{
+		"  # group Fake Group 01 (00g1emaKYZTWRYYRRTSK)\n  + new@example.com (00unew)\n  - user2@example.com (00gg0xVALADWBPXOFZAK)\n",
+		"  ~ user1@example.com (00gg0xVALADWBPXOFZAS) kept, group is managed by a group rule\n",
+		"Plan: 1 to add, 1 to remove, 2 protected.",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("plan missing %q:\n%s", want, buf.String())
+		}
+	}
+	if err := applyMembership(client, changes, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(client.added, ",") != "00g1emaKYZTWRYYRRTSK/00unew" {
+		t.Errorf("added = %v", client.added)
+	}
+	if strings.Join(client.removed, ",") != "00g1emaKYZTWRYYRRTSK/00gg0xVALADWBPXOFZAK" {
+		t.Errorf("removed = %v", client.removed)
+	}
+}
+
+func TestLoadDesiredGroupsInvalid(t *testing.T) {
+	for name, groups := range map[string]string{
+		"missing members":    "groups:\n  - id: 00g1emaKYZTWRYYRRTSK\n",
+		"misspelled members": "groups:\n  - id: 00g1emaKYZTWRYYRRTSK\n    member:\n      - user0@example.com\n",
+		"null members":       "groups:\n  - id: 00g1emaKYZTWRYYRRTSK\n    members:\n",
+		"duplicate id":       "groups:\n  - id: 00g1emaKYZTWRYYRRTSK\n    members: []\n  - id: 00g1emaKYZTWRYYRRTSK\n    members: [user0@example.com]\n",
+	} {
+		if _, err := loadDesiredGroups(writeTestFile(t, "groups.yaml", groups)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	desired, err := loadDesiredGroups(writeTestFile(t, "groups.yaml", "groups:\n  - id: 00g1emaKYZTWRYYRRTSK\n    members: []\n"))
+	if err != nil || len(desired.Groups) != 1 || desired.Groups[0].Members == nil {
+		t.Errorf("explicitly empty group = %+v, %v", desired, err)
+	}
+}
+
+func TestPlanMembershipUnknownUser(t *testing.T) {
+	desired := &desiredGroups{Groups: []desiredGroup{{ID: "00g1emaKYZTWRYYRRTSK", Members: []string{"missing@example.com"}}}}
+	if _, err := planMembership(&MockOktaClient{}, desired, false); err == nil {
+		t.Error("expected error for unknown member")
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	if !confirm(strings.NewReader("yes\n"), &bytes.Buffer{}, "Continue?") {
+		t.Error("confirm(yes) = false")
+	}
+	if confirm(strings.NewReader("y\n"), &bytes.Buffer{}, "Continue?") {
+		t.Error("confirm(y) = true, only yes should be accepted")
+	}
+}
+
+// assertRequiresLiveOrg runs the command given by args with --from-snapshot and checks that it
+// is rejected before it reads or changes anything.
+func assertRequiresLiveOrg(t *testing.T, args ...string) {
+	t.Helper()
+	t.Cleanup(func() { snapshotDir = ""; rootCmd.SetOut(nil); rootCmd.SetErr(nil); rootCmd.SetArgs(nil) })
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	rootCmd.SetArgs(append(args, "--from-snapshot", t.TempDir()))
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "cannot be used with --from-snapshot") {
+		t.Errorf("%s --from-snapshot = %v, want an error", strings.Join(args, " "), err)
+	}
+}
+
+func TestPlanFromSnapshot(t *testing.T) {
+	assertRequiresLiveOrg(t, "plan", "-f", "groups.yaml")
+}
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/flynshue/oktactl/pkg/okta-api/v2/oktaapi"
+)
+
+const (
+	lifecycleSuspended   = "suspended"
+	lifecycleDeactivated = "deactivated"
+)
+
+// offboardOptions selects what `oktactl user offboard` does beyond removing group memberships and clearing sessions.
+type offboardOptions struct {
+	DryRun     bool
+	Suspend    bool
+	Deactivate bool
+}
+
+// offboardReceipt records a user's access before offboarding and every change that was made.
+// `oktactl user restore` reads it to undo the offboarding.
+type offboardReceipt struct {
+	User            oktaapi.User    `json:"user"`
+	StartedAt       time.Time       `json:"startedAt"`
+	FinishedAt      time.Time       `json:"finishedAt"`
+	DryRun          bool            `json:"dryRun"`
+	Groups          []oktaapi.Group `json:"groups"`
+	Apps            []oktaapi.App   `json:"apps"`
+	RemovedGroups   []oktaapi.Group `json:"removedGroups"`
+	SkippedGroups   []skippedGroup  `json:"skippedGroups,omitempty"`
+	SessionsCleared bool            `json:"sessionsCleared"`
+	Lifecycle       string          `json:"lifecycle,omitempty"`
+	Error           string          `json:"error,omitempty"`
+}
+
+// offboardUser captures the user's groups and apps, removes their direct group memberships,
+// clears their sessions and optionally suspends or deactivates them. The receipt is returned
+// even when a step fails so that completed changes can still be restored.
+func offboardUser(admin OktaUserAdmin, ref string, opts offboardOptions, w io.Writer) (*offboardReceipt, error) {
+	if opts.Suspend && opts.Deactivate {
+		return nil, fmt.Errorf("--suspend and --deactivate cannot be used together")
+	}
+	user, err := oktaapi.ResolveUser(admin, ref)
+	if err != nil {
+		return nil, fmt.Errorf("user %s: %w", ref, err)
+	}
+	receipt := &offboardReceipt{User: user, StartedAt: time.Now().UTC(), DryRun: opts.DryRun, RemovedGroups: []oktaapi.Group{}}
+	err = offboardSteps(admin, receipt, opts, w)
+	receipt.FinishedAt = time.Now().UTC()
+	if err != nil {
+		receipt.Error = err.Error()
+	}
+	return receipt, err
+}
+
+func offboardSteps(admin OktaUserAdmin, receipt *offboardReceipt, opts offboardOptions, w io.Writer) error {
+	user := receipt.User
+	prefix := ""
+	if opts.DryRun {
+		prefix = "[dry-run] "
+	}
+	var err error
+	if receipt.Groups, err = admin.ListOktaUserGroups(user.ID); err != nil {
+		return err
+	}
+	if receipt.Apps, err = admin.ListOktaUserApps(user.ID); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%soffboarding %s: %d groups, %d apps\n", prefix, userLabel(user), len(receipt.Groups), len(receipt.Apps))
+	ruleManaged, err := ruleManagedGroups(admin)
+	if err != nil {
+		return err
+	}
+	for _, group := range receipt.Groups {
+		label := desiredGroup{ID: group.ID, Name: group.Name}.label()
+		if reason := directGroupReason(group, ruleManaged); reason != "" {
+			receipt.SkippedGroups = append(receipt.SkippedGroups, skippedGroup{Group: group, Reason: reason})
+			fmt.Fprintf(w, "%sskipping group %s: %s\n", prefix, label, reason)
+			continue
+		}
+		if !opts.DryRun {
+			if err := admin.RemoveOktaGroupUser(group.ID, user.ID); err != nil {
+				return fmt.Errorf("removing from group %s: %w", label, err)
+			}
+		}
+		receipt.RemovedGroups = append(receipt.RemovedGroups, group)
+		fmt.Fprintf(w, "%sremoved from group %s\n", prefix, label)
+	}
+	if !opts.DryRun {
+		if err := admin.ClearOktaUserSessions(user.ID); err != nil {
+			return fmt.Errorf("clearing sessions: %w", err)
+		}
+	}
+	receipt.SessionsCleared = true
+	fmt.Fprintf(w, "%scleared sessions\n", prefix)
+	switch {
+	case opts.Suspend:
+		if !opts.DryRun {
+			if err := admin.SuspendOktaUser(user.ID); err != nil {
+				return fmt.Errorf("suspending user: %w", err)
+			}
+		}
+		receipt.Lifecycle = lifecycleSuspended
+	case opts.Deactivate:
+		if !opts.DryRun {
+			if err := admin.DeactivateOktaUser(user.ID); err != nil {
+				return fmt.Errorf("deactivating user: %w", err)
+			}
+		}
+		receipt.Lifecycle = lifecycleDeactivated
+	}
+	if receipt.Lifecycle != "" {
+		fmt.Fprintf(w, "%s%s user\n", prefix, receipt.Lifecycle)
+	}
+	return nil
+}
+
+// restoreUser undoes the changes recorded in an offboarding receipt.
+func restoreUser(admin OktaUserAdmin, receipt *offboardReceipt, dryRun bool, w io.Writer) error {
+	if receipt.DryRun {
+		return fmt.Errorf("receipt is from a dry run, nothing to restore")
+	}
+	prefix := ""
+	if dryRun {
+		prefix = "[dry-run] "
+	}
+	user := receipt.User
+	switch receipt.Lifecycle {
+	case lifecycleSuspended:
+		if !dryRun {
+			if err := admin.UnsuspendOktaUser(user.ID); err != nil {
+				return fmt.Errorf("unsuspending user: %w", err)
+			}
+		}
+		fmt.Fprintf(w, "%sunsuspended %s\n", prefix, userLabel(user))
+	case lifecycleDeactivated:
+		// Activating without an email leaves the user PROVISIONED until they set a password,
+		// which only they can do, so the operator is told what is left.
+		status := "PROVISIONED"
+		if !dryRun {
+			if err := admin.ActivateOktaUser(user.ID); err != nil {
+				return fmt.Errorf("activating user: %w", err)
+			}
+			if activated, err := admin.GetUserById(user.ID); err == nil {
+				status = activated.Status
+			}
+		}
+		fmt.Fprintf(w, "%sactivated %s\n", prefix, userLabel(user))
+		if status == "PROVISIONED" {
+			fmt.Fprintf(w, "%s%s is PROVISIONED, not ACTIVE, until they set a password: send them an activation email with Resend Activation Email on their page in the Okta Admin Console\n", prefix, userLabel(user))
+		}
+	}
+	for _, group := range receipt.RemovedGroups {
+		label := desiredGroup{ID: group.ID, Name: group.Name}.label()
+		if !dryRun {
+			if err := admin.AddOktaGroupUser(group.ID, user.ID); err != nil {
+				return fmt.Errorf("adding to group %s: %w", label, err)
+			}
+		}
+		fmt.Fprintf(w, "%sadded %s to group %s\n", prefix, userLabel(user), label)
+	}
+	return nil
+}
+
+func writeReceipt(path string, receipt *offboardReceipt) error {
+	b, err := json.MarshalIndent(receipt, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o600)
+}
+
+func readReceipt(path string) (*offboardReceipt, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	receipt := &offboardReceipt{}
+	if err := json.Unmarshal(b, receipt); err != nil {
+		return nil, fmt.Errorf("reading receipt %s: %w", path, err)
+	}
+	return receipt, nil
+}
+
+func runUserOffboard(w io.Writer, admin OktaUserAdmin, ref string, opts offboardOptions, receiptPath string) error {
+	receipt, err := offboardUser(admin, ref, opts, w)
+	if receipt == nil {
+		return err
+	}
+	if receiptPath == "" {
+		receiptPath = fmt.Sprintf("offboard-%s-%s.json", receipt.User.ID, receipt.StartedAt.Format("20060102T150405Z"))
+	}
+	if werr := writeReceipt(receiptPath, receipt); werr != nil {
+		if err != nil {
+			return fmt.Errorf("%w (writing receipt: %s)", err, werr)
+		}
+		return werr
+	}
+	fmt.Fprintf(w, "wrote receipt %s\n", receiptPath)
+	return err
+}
+
+func runUserRestore(w io.Writer, admin OktaUserAdmin, receiptPath string, dryRun bool) error {
+	receipt, err := readReceipt(receiptPath)
+	if err != nil {
+		return err
+	}
+	return restoreUser(admin, receipt, dryRun, w)
+}
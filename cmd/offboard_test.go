@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOffboardRestore(t *testing.T) {
+	client := &MockOktaClient{}
+	receipt, err := offboardUser(client, "source@example.com", offboardOptions{Suspend: true}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(client.removed, ","); got != "00g1emaKYZTWRYYRRTSK/00usource,00gg0xVALADWBPXOFZAK/00usource" {
+		t.Errorf("removed = %s", got)
+	}
+	if got := strings.Join(client.calls, ","); got != "clear-sessions/00usource,suspend/00usource" {
+		t.Errorf("calls = %s", got)
+	}
+	if len(receipt.Groups) != 5 || len(receipt.Apps) != 2 || len(receipt.SkippedGroups) != 3 || receipt.Lifecycle != lifecycleSuspended {
+		t.Errorf("unexpected receipt %+v", receipt)
+	}
+
+	path := filepath.Join(t.TempDir(), "receipt.json")
+	if err := writeReceipt(path, receipt); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := readReceipt(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restorer := &MockOktaClient{}
+	if err := restoreUser(restorer, saved, false, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(restorer.added, ","); got != "00g1emaKYZTWRYYRRTSK/00usource,00gg0xVALADWBPXOFZAK/00usource" {
+		t.Errorf("restored groups = %s", got)
+	}
+	if got := strings.Join(restorer.calls, ","); got != "unsuspend/00usource" {
+		t.Errorf("restore calls = %s", got)
+	}
+}
+
+func TestOffboardDryRun(t *testing.T) {
+	client := &MockOktaClient{}
+	buf := &bytes.Buffer{}
+	receipt, err := offboardUser(client, "source@example.com", offboardOptions{DryRun: true, Deactivate: true}, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(client.removed) != 0 || len(client.calls) != 0 {
+		t.Errorf("dry run made changes: removed %v, calls %v", client.removed, client.calls)
+	}
+	if !receipt.DryRun || len(receipt.RemovedGroups) != 2 || receipt.Lifecycle != lifecycleDeactivated {
+		t.Errorf("unexpected receipt %+v", receipt)
+	}
+	if !strings.Contains(buf.String(), "[dry-run] deactivated user") {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+	if err := restoreUser(client, receipt, false, &bytes.Buffer{}); err == nil {
+		t.Error("expected error restoring from a dry run receipt")
+	}
+}
+
+func TestUserRestoreDryRunIsOwnFlag(t *testing.T) {
+	t.Cleanup(func() { userRestoreCmd.Flags().Set("dry-run", "false") })
+	if err := userRestoreCmd.Flags().Set("dry-run", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if offboardOpts.DryRun {
+		t.Error("user restore --dry-run also set user offboard --dry-run")
+	}
+}
+
+func TestOffboardRestoreFromSnapshot(t *testing.T) {
+	assertRequiresLiveOrg(t, "user", "offboard", "source@example.com")
+	assertRequiresLiveOrg(t, "user", "restore", "receipt.json")
+}
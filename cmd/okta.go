@@ -1,12 +1,22 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"iter"
 	"log"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"text/tabwriter"
+	"time"
+	"unicode/utf8"
 
+	"github.com/flynshue/oktactl/pkg/httprecord"
 	"github.com/flynshue/oktactl/pkg/okta-api/v2/oktaapi"
+	"github.com/okta/okta-sdk-golang/v2/okta"
 	"github.com/spf13/viper"
 )
 
@@ -19,97 +29,345 @@ type OktaService interface {
 	ListApps(name string) ([]oktaapi.App, error)
 	GetAppById(appID string) (oktaapi.App, error)
 	ListAppsGroups(appID string) (oktaapi.App, []oktaapi.GroupAssignmentResp, error)
+	ListAppUsers(appID string) ([]oktaapi.AppUser, error)
 	ListOktaGroups(name string) ([]oktaapi.Group, error)
 	ListOktaGroupUsers(groupID string) ([]oktaapi.User, error)
 }
 
-func listApps(os OktaService, name string) error {
-	apps, err := os.ListApps(name)
-	if err != nil {
-		return err
+// OktaUserLookup finds users and their access. Only the live client implements it.
+type OktaUserLookup interface {
+	GetUserById(userID string) (oktaapi.User, error)
+	ListOktaUsers(search string) ([]oktaapi.User, error)
+	ListOktaUserGroups(userID string) ([]oktaapi.Group, error)
+	ListOktaUserApps(userID string) ([]oktaapi.App, error)
+}
+
+// OktaGroupAdmin changes group membership. Only the live client implements it.
+type OktaGroupAdmin interface {
+	OktaService
+	OktaUserLookup
+	ListOktaGroupRules() ([]oktaapi.GroupRule, error)
+	AddOktaGroupUser(groupID, userID string) error
+	RemoveOktaGroupUser(groupID, userID string) error
+}
+
+// OktaUserAdmin changes user sessions and lifecycle. Only the live client implements it.
+type OktaUserAdmin interface {
+	OktaGroupAdmin
+	ClearOktaUserSessions(userID string) error
+	SuspendOktaUser(userID string) error
+	UnsuspendOktaUser(userID string) error
+	DeactivateOktaUser(userID string) error
+	ActivateOktaUser(userID string) error
+}
+
+// oktaIterator streams lists page by page. The live client implements it; the list commands
+// use it when they can so that rows are printed as they arrive.
+type oktaIterator interface {
+	IterApps(ctx context.Context, name string) iter.Seq2[oktaapi.App, error]
+	IterGroups(ctx context.Context, name string) iter.Seq2[oktaapi.Group, error]
+	IterGroupUsers(ctx context.Context, groupID string) iter.Seq2[oktaapi.User, error]
+}
+
+// listed returns an iterator over the result of a List method.
+func listed[T any](items []T, err error) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		if err != nil {
+			var zero T
+			yield(zero, err)
+			return
+		}
+		for _, item := range items {
+			if !yield(item, nil) {
+				return
+			}
+		}
 	}
-	if len(apps) == 0 {
-		fmt.Printf("no apps found using keyword %s\n", name)
+}
+
+// streamRows is how many rows are printed together by printRows. The header and the first
+// block set the column widths, which later blocks keep so that a long list stays aligned.
+const streamRows = 100
+
+// printRows prints header and a row for each item of seq as the items arrive, aligned like
+// newTabWriter does. Nothing is printed when seq is empty. A cell wider than its column in a
+// later block only shifts the rest of its own row. It returns the number of rows printed.
+func printRows[T any](out io.Writer, header string, seq iter.Seq2[T, error], row func(T) string) (int, error) {
+	var block strings.Builder
+	var widths []int
+	flush := func() {
+		lines := strings.Split(strings.TrimSuffix(block.String(), "\n"), "\n")
+		if widths == nil {
+			widths = cellWidths(lines)
+		}
+		for _, line := range lines {
+			cells := strings.Split(line, "\t")
+			for i, cell := range cells[:len(cells)-1] {
+				pad := 2
+				if i < len(widths) && widths[i] > utf8.RuneCountInString(cell) {
+					pad += widths[i] - utf8.RuneCountInString(cell)
+				}
+				fmt.Fprint(out, cell, strings.Repeat(" ", pad))
+			}
+			fmt.Fprintln(out, cells[len(cells)-1])
+		}
+		block.Reset()
 	}
-	w := newTabWriter()
-	fmt.Fprintln(w, "Okta App ID\t Name\t")
-	for _, app := range apps {
-		fmt.Fprintf(w, "%s\t %s\t\n", app.ID, app.Label)
+	n := 0
+	defer func() {
+		if block.Len() > 0 {
+			flush()
+		}
+	}()
+	for item, err := range seq {
+		if err != nil {
+			return n, err
+		}
+		if n == 0 {
+			fmt.Fprintln(&block, header)
+		}
+		block.WriteString(row(item))
+		n++
+		if n%streamRows == 0 {
+			flush()
+		}
+	}
+	return n, nil
+}
+
+// cellWidths returns the width of the widest cell of each tab-terminated column of lines.
+func cellWidths(lines []string) []int {
+	widths := []int{}
+	for _, line := range lines {
+		cells := strings.Split(line, "\t")
+		for i, cell := range cells[:len(cells)-1] {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
 	}
+	return widths
+}
+
+func printHeader(out io.Writer, header string) {
+	w := newTabWriter(out)
+	fmt.Fprintln(w, header)
 	w.Flush()
+}
+
+func listApps(ctx context.Context, out io.Writer, os OktaService, name string) error {
+	var apps iter.Seq2[oktaapi.App, error]
+	if it, ok := os.(oktaIterator); ok {
+		apps = it.IterApps(ctx, name)
+	} else {
+		apps = listed(os.ListApps(name))
+	}
+	const header = "Okta App ID\t Name\t"
+	n, err := printRows(out, header, apps, func(app oktaapi.App) string {
+		return fmt.Sprintf("%s\t %s\t\n", app.ID, app.Label)
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		fmt.Fprintf(out, "no apps found using keyword %s\n", name)
+		printHeader(out, header)
+	}
 	return nil
 }
 
-func getAppById(os OktaService, appID string) error {
+func getAppById(out io.Writer, os OktaService, appID string) error {
 	app, err := os.GetAppById(appID)
 	if err != nil {
 		return err
 	}
-	w := newTabWriter()
+	w := newTabWriter(out)
 	fmt.Fprintln(w, "Okta App ID\t Name\t")
 	fmt.Fprintf(w, "%s\t %s\t\n", app.ID, app.Label)
 	w.Flush()
 	return nil
 }
 
-func listAppsGroups(os OktaService, appID string) error {
+func listAppsGroups(out io.Writer, os OktaService, appRef string) error {
+	appID, err := oktaapi.ResolveAppID(os, appRef)
+	if err != nil {
+		return err
+	}
 	app, groups, err := os.ListAppsGroups(appID)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Group assignment for %s %s\n", app.ID, app.Label)
-	fmt.Printf("groups %d\n", len(groups))
+	fmt.Fprintf(out, "Group assignment for %s %s\n", app.ID, app.Label)
+	fmt.Fprintf(out, "groups %d\n", len(groups))
 	for _, group := range groups {
-		fmt.Printf("%s  %s\n", group.GroupID, group.Name)
+		fmt.Fprintf(out, "%s  %s\n", group.GroupID, group.Name)
 		for _, roles := range group.SAMLRoles {
-			fmt.Println(roles)
+			fmt.Fprintln(out, roles)
 		}
 		if group.Role != "" {
-			fmt.Println(group.Role)
+			fmt.Fprintln(out, group.Role)
 		}
 	}
 	return nil
 }
 
-func listOktaGroups(os OktaService, keyword string) error {
-	groups, err := os.ListOktaGroups(keyword)
+func listOktaGroups(ctx context.Context, out io.Writer, os OktaService, keyword string) error {
+	var groups iter.Seq2[oktaapi.Group, error]
+	if it, ok := os.(oktaIterator); ok {
+		groups = it.IterGroups(ctx, keyword)
+	} else {
+		groups = listed(os.ListOktaGroups(keyword))
+	}
+	const header = "Okta Group ID\t Name\t"
+	n, err := printRows(out, header, groups, func(group oktaapi.Group) string {
+		return fmt.Sprintf("%s\t %s\t\n", group.ID, group.Name)
+	})
 	if err != nil {
 		return err
 	}
-	w := newTabWriter()
-	fmt.Fprintln(w, "Okta Group ID\t Name\t")
-	for _, group := range groups {
-		fmt.Fprintf(w, "%s\t %s\t\n", group.ID, group.Name)
+	if n == 0 {
+		printHeader(out, header)
 	}
-	w.Flush()
 	return nil
 }
 
-func listOktaGroupUsers(os OktaService, groupID string) error {
-	users, err := os.ListOktaGroupUsers(groupID)
+func listOktaGroupUsers(ctx context.Context, out io.Writer, os OktaService, groupRef string) error {
+	groupID, err := oktaapi.ResolveGroupID(os, groupRef)
 	if err != nil {
 		return err
 	}
-	w := newTabWriter()
-	fmt.Fprintln(w, "Okta User ID\t First Name\t Last Name\t Email\t")
-	for _, user := range users {
-		fmt.Fprintf(w, "%s\t %s\t %s\t %s\n", user.ID, user.FirstName, user.LastName, user.Email)
+	var users iter.Seq2[oktaapi.User, error]
+	if it, ok := os.(oktaIterator); ok {
+		users = it.IterGroupUsers(ctx, groupID)
+	} else {
+		users = listed(os.ListOktaGroupUsers(groupID))
+	}
+	const header = "Okta User ID\t First Name\t Last Name\t Email\t"
+	n, err := printRows(out, header, users, func(user oktaapi.User) string {
+		return fmt.Sprintf("%s\t %s\t %s\t %s\n", user.ID, user.FirstName, user.LastName, user.Email)
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		printHeader(out, header)
 	}
-	w.Flush()
 	return nil
 }
 
 func newClient() *oktaapi.OktaClient {
+	return connect(false)
+}
+
+// newUncachedClient returns the live client reading everything from the API, for commands that
+// write or poll and so must act on the org as it is now. It goes without the SDK's in-memory
+// response cache, which answers repeated reads for five minutes, and without the responses
+// cached on disk by cache_ttl, though its writes still drop the ones they change.
+func newUncachedClient() *oktaapi.OktaClient {
+	return connect(true, okta.WithCache(false))
+}
+
+// connect creates the client shared by the command, applying extra after the transport options.
+func connect(uncached bool, extra ...okta.ConfigSetter) *oktaapi.OktaClient {
 	if client != nil {
 		return client
 	}
-	client, err = oktaapi.NewClient(viper.GetString("org"), viper.GetString("token"))
+	org, token := viper.GetString("org"), viper.GetString("token")
+	opts, err := transportOptions(uncached)
+	if err != nil {
+		log.Fatal(err)
+	}
+	opts = append(opts, extra...)
+	if replayDir != "" {
+		// Replaying needs no credentials, so fixtures can be shared with people who have none.
+		org, token = httprecord.ReplayOrg, "replay"
+	}
+	client, err = oktaapi.NewClient(org, token, opts...)
 	if err != nil {
 		log.Fatal(err)
 	}
 	return client
 }
 
-func newTabWriter() *tabwriter.Writer {
-	return tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.TabIndent)
+// transportOptions returns the client options for --record, --replay and the response cache set
+// by cache_ttl. When recording or replaying, the SDK's response cache is turned off so that every
+// request is recorded, and replayed in the same order. An uncached client refreshes the cache
+// set by cache_ttl instead of reading from it.
+func transportOptions(uncached bool) ([]okta.ConfigSetter, error) {
+	switch {
+	case recordDir != "" && replayDir != "":
+		return nil, fmt.Errorf("--record and --replay cannot be used together")
+	case recordDir != "":
+		rec, err := httprecord.NewRecorder(recordDir, nil)
+		if err != nil {
+			return nil, err
+		}
+		return []okta.ConfigSetter{okta.WithCache(false), httpClientWith(rec)}, nil
+	case replayDir != "":
+		rep, err := httprecord.NewReplayer(replayDir)
+		if err != nil {
+			return nil, err
+		}
+		return []okta.ConfigSetter{okta.WithCache(false), httpClientWith(rep)}, nil
+	case !noCache && viper.GetDuration("cache_ttl") > 0:
+		dir, err := httpCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		cache := oktaapi.NewCacheTransport(dir, viper.GetDuration("cache_ttl"), nil)
+		cache.Refresh = uncached
+		return []okta.ConfigSetter{httpClientWith(cache)}, nil
+	}
+	return nil, nil
+}
+
+// httpTimeout bounds each request, as the SDK does with its default connection timeout for
+// the clients it builds itself.
+const httpTimeout = 60 * time.Second
+
+// httpClientWith has the SDK send requests with rt.
+func httpClientWith(rt http.RoundTripper) okta.ConfigSetter {
+	return okta.WithHttpClientPtr(&http.Client{Transport: rt, Timeout: httpTimeout})
+}
+
+// httpCacheDir is where API responses are cached when cache_ttl is set.
+func httpCacheDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".oktactl", "cache", "http"), nil
+}
+
+// newService returns the snapshot named by --from-snapshot, or the live client when no snapshot is set.
+func newService() OktaService {
+	if snapshotDir == "" {
+		return newClient()
+	}
+	return loadSnapshot()
+}
+
+func loadSnapshot() OktaService {
+	snap, err := oktaapi.LoadSnapshot(snapshotDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return snap
+}
+
+func exportSnapshot(w io.Writer, oc *oktaapi.OktaClient, dir string) error {
+	snap, err := oc.Snapshot()
+	if err != nil {
+		return err
+	}
+	if err := snap.Save(dir); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "exported %d apps and %d groups to %s\n", len(snap.Apps), len(snap.Groups), dir)
+	return nil
+}
+
+func newTabWriter(w io.Writer) *tabwriter.Writer {
+	return tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.TabIndent)
 }
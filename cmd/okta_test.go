@@ -1,12 +1,22 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
 	"testing"
 
 	"github.com/flynshue/oktactl/pkg/okta-api/v2/oktaapi"
 )
 
-type MockOktaClient struct{}
+type MockOktaClient struct {
+	added   []string
+	removed []string
+	calls   []string
+}
 
 func (m *MockOktaClient) ListApps(name string) ([]oktaapi.App, error) {
 	return []oktaapi.App{
@@ -47,6 +57,14 @@ func (m *MockOktaClient) ListAppsGroups(appID string) (oktaapi.App, []oktaapi.Gr
 		}, nil
 }
 
+func (m *MockOktaClient) ListAppUsers(appID string) ([]oktaapi.AppUser, error) {
+	direct := oktaapi.AppUser{ID: "00u1hqieohhlPBv581d8", Scope: "USER", Status: "ACTIVE"}
+	direct.Embedded.User = oktaapi.User{ID: "00u1hqieohhlPBv581d8", Status: "ACTIVE", Profile: oktaapi.Profile{FirstName: "Direct", LastName: "User", Email: "direct@example.com"}}
+	viaGroup := oktaapi.AppUser{ID: "00g1emaKYZTWRYYRRTSK", Scope: "GROUP", Status: "ACTIVE"}
+	viaGroup.Embedded.User = oktaapi.User{ID: "00g1emaKYZTWRYYRRTSK", Status: "ACTIVE", Profile: oktaapi.Profile{FirstName: "Test", LastName: "User-0", Email: "user0@example.com"}}
+	return []oktaapi.AppUser{direct, viaGroup}, nil
+}
+
 func (m *MockOktaClient) ListOktaGroups(name string) ([]oktaapi.Group, error) {
 	return []oktaapi.Group{
 		{ID: "00g1emaKYZTWRYYRRTSK", Profile: oktaapi.Profile{Name: "Fake Group 01"}},
@@ -57,38 +75,191 @@ func (m *MockOktaClient) ListOktaGroups(name string) ([]oktaapi.Group, error) {
 
 func (m *MockOktaClient) ListOktaGroupUsers(groupID string) ([]oktaapi.User, error) {
 	return []oktaapi.User{
-		{ID: "00g1emaKYZTWRYYRRTSK", Profile: oktaapi.Profile{FirstName: "Test", LastName: "User-0", Email: "user0@example.com"}},
-		{ID: "00gg0xVALADWBPXOFZAS", Profile: oktaapi.Profile{FirstName: "Test", LastName: "User_1", Email: "user1@example.com"}},
-		{ID: "00gg0xVALADWBPXOFZAK", Profile: oktaapi.Profile{FirstName: "Test", LastName: "User'2", Email: "user2@example.com"}},
+		{ID: "00g1emaKYZTWRYYRRTSK", Status: "ACTIVE", LastLogin: "2024-01-02T15:04:05.000Z", Profile: oktaapi.Profile{FirstName: "Test", LastName: "User-0", Email: "user0@example.com"}},
+		{ID: "00gg0xVALADWBPXOFZAS", Status: "SUSPENDED", LastLogin: "2023-06-01T09:00:00.000Z", Profile: oktaapi.Profile{FirstName: "Test", LastName: "User_1", Email: "user1@example.com"}},
+		{ID: "00gg0xVALADWBPXOFZAK", Status: "ACTIVE", Profile: oktaapi.Profile{FirstName: "Test", LastName: "User'2", Email: "user2@example.com"}},
 	}, nil
 }
 
+func (m *MockOktaClient) GetUserById(userID string) (oktaapi.User, error) {
+	if strings.HasPrefix(userID, "missing") {
+		return oktaapi.User{}, fmt.Errorf("user %s not found", userID)
+	}
+	login := strings.TrimPrefix(userID, "00u")
+	return oktaapi.User{ID: "00u" + strings.Split(login, "@")[0], Status: "ACTIVE", Profile: oktaapi.Profile{Login: login, Email: login}}, nil
+}
+
+func (m *MockOktaClient) ListOktaUsers(search string) ([]oktaapi.User, error) {
+	return []oktaapi.User{}, nil
+}
+
+func (m *MockOktaClient) ListOktaUserGroups(userID string) ([]oktaapi.Group, error) {
+	groups := []oktaapi.Group{
+		{ID: "00g00000000000000000", Type: "BUILT_IN", Profile: oktaapi.Profile{Name: "Everyone"}},
+		{ID: "00g1emaKYZTWRYYRRTSK", Type: "OKTA_GROUP", Profile: oktaapi.Profile{Name: "Fake Group 01"}},
+	}
+	if userID == "00usource" {
+		groups = append(groups,
+			oktaapi.Group{ID: "00gg0xVALADWBPXOFZAS", Type: "OKTA_GROUP", Profile: oktaapi.Profile{Name: "Fake Group 02"}},
+			oktaapi.Group{ID: "00gg0xVALADWBPXOFZAK", Type: "OKTA_GROUP", Profile: oktaapi.Profile{Name: "Fake Group 03"}},
+			oktaapi.Group{ID: "00gappgroup000000000", Type: "APP_GROUP", Profile: oktaapi.Profile{Name: "AD Group"}},
+		)
+	}
+	return groups, nil
+}
+
+func (m *MockOktaClient) ListOktaUserApps(userID string) ([]oktaapi.App, error) {
+	apps := []oktaapi.App{{ID: "0oa1gjh63g214q0Hq0g4", Name: "testorgone_customsaml20app_1", Label: "Test Custom Saml 2.0 App"}}
+	if userID == "00usource" {
+		apps = append(apps, oktaapi.App{ID: "0oabkvBLDEKCNXBGYUAS", Name: "template_swa", Label: "Test Sample Plugin App"})
+	}
+	return apps, nil
+}
+
+func (m *MockOktaClient) ListOktaGroupRules() ([]oktaapi.GroupRule, error) {
+	rule := oktaapi.GroupRule{ID: "0pr3f7zMZZHPgUoWO0g4", Name: "Engineering group rule", Status: "ACTIVE"}
+	rule.Actions.AssignUserToGroups.GroupIDs = []string{"00gg0xVALADWBPXOFZAS"}
+	return []oktaapi.GroupRule{rule}, nil
+}
+
+func (m *MockOktaClient) AddOktaGroupUser(groupID, userID string) error {
+	m.added = append(m.added, groupID+"/"+userID)
+	return nil
+}
+
+func (m *MockOktaClient) RemoveOktaGroupUser(groupID, userID string) error {
+	m.removed = append(m.removed, groupID+"/"+userID)
+	return nil
+}
+
+func (m *MockOktaClient) ClearOktaUserSessions(userID string) error {
+	m.calls = append(m.calls, "clear-sessions/"+userID)
+	return nil
+}
+
+func (m *MockOktaClient) SuspendOktaUser(userID string) error {
+	m.calls = append(m.calls, "suspend/"+userID)
+	return nil
+}
+
+func (m *MockOktaClient) UnsuspendOktaUser(userID string) error {
+	m.calls = append(m.calls, "unsuspend/"+userID)
+	return nil
+}
+
+func (m *MockOktaClient) DeactivateOktaUser(userID string) error {
+	m.calls = append(m.calls, "deactivate/"+userID)
+	return nil
+}
+
+func (m *MockOktaClient) ActivateOktaUser(userID string) error {
+	m.calls = append(m.calls, "activate/"+userID)
+	return nil
+}
+
 func TestListApps(t *testing.T) {
-	if err := listApps(&MockOktaClient{}, "test"); err != nil {
+	if err := listApps(context.Background(), io.Discard, &MockOktaClient{}, "test"); err != nil {
 		t.Error(err)
 	}
 }
 
 func TestGetAppByID(t *testing.T) {
-	if err := getAppById(&MockOktaClient{}, "0oa1gjh63g214q0Hq0g4"); err != nil {
+	if err := getAppById(io.Discard, &MockOktaClient{}, "0oa1gjh63g214q0Hq0g4"); err != nil {
 		t.Error(err)
 	}
 }
 
 func TestListAppsGroups(t *testing.T) {
-	if err := listAppsGroups(&MockOktaClient{}, "0oa1gjh63g214q0Hq0g4"); err != nil {
+	if err := listAppsGroups(io.Discard, &MockOktaClient{}, "0oa1gjh63g214q0Hq0g4"); err != nil {
 		t.Error(err)
 	}
 }
 
 func TestListOktaGroups(t *testing.T) {
-	if err := listOktaGroups(&MockOktaClient{}, "test"); err != nil {
+	if err := listOktaGroups(context.Background(), io.Discard, &MockOktaClient{}, "test"); err != nil {
 		t.Error(err)
 	}
 }
 
 func TestListOktaGroupUsers(t *testing.T) {
-	if err := listOktaGroupUsers(&MockOktaClient{}, "0oa1gjh63g214q0Hq0g4"); err != nil {
+	if err := listOktaGroupUsers(context.Background(), io.Discard, &MockOktaClient{}, "00g1emaKYZTWRYYRRTSK"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestListOktaGroupUsersByName(t *testing.T) {
+	if err := listOktaGroupUsers(context.Background(), io.Discard, &MockOktaClient{}, "fake group 02"); err != nil {
+		t.Error(err)
+	}
+	err := listOktaGroupUsers(context.Background(), io.Discard, &MockOktaClient{}, "Fake")
+	if err == nil || !strings.Contains(err.Error(), `group "Fake" is ambiguous, it matches 3 groups`) {
+		t.Errorf("error = %v, want ambiguous group", err)
+	}
+}
+
+func TestPrintRowsStreams(t *testing.T) {
+	buf := &bytes.Buffer{}
+	printedEarly := false
+	seq := func(yield func(int, error) bool) {
+		for i := 1; i <= streamRows+1; i++ {
+			if i == streamRows+1 {
+				printedEarly = strings.Count(buf.String(), "\n") == streamRows+1
+			}
+			if !yield(i, nil) {
+				return
+			}
+		}
+		yield(0, errors.New("page failed"))
+	}
+	n, err := printRows(buf, "N\t", seq, func(i int) string { return fmt.Sprintf("%d\t\n", i) })
+	if err == nil || n != streamRows+1 {
+		t.Errorf("printRows = %d, %v, want %d rows and the error", n, err, streamRows+1)
+	}
+	if !printedEarly {
+		t.Error("the first rows were not printed before the rest arrived")
+	}
+	if !strings.Contains(buf.String(), fmt.Sprintf("\n%d ", streamRows+1)) {
+		t.Errorf("rows before the error were not printed:\n%s", buf)
+	}
+}
+
+func TestPrintRowsKeepsColumnWidths(t *testing.T) {
+	buf := &bytes.Buffer{}
+	ids := []string{"00g1first-and-widest"}
+	for i := 2; i <= 2*streamRows+50; i++ {
+		ids = append(ids, fmt.Sprintf("00g%d", i))
+	}
+	ids[streamRows+10] = "00g1wider-than-the-first-block"
+	n, err := printRows(buf, "Okta Group ID\t Name\t", listed(ids, nil), func(id string) string {
+		return fmt.Sprintf("%s\t %s\t\n", id, "group")
+	})
+	if err != nil || n != len(ids) {
+		t.Fatalf("printRows = %d, %v, want %d rows", n, err, len(ids))
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(ids)+1 {
+		t.Fatalf("got %d lines, want %d", len(lines), len(ids)+1)
+	}
+	col := strings.Index(lines[0], " Name")
+	if col != len("00g1first-and-widest")+2 {
+		t.Fatalf("header = %q, want the Name column after the widest ID of the first block", lines[0])
+	}
+	for i, line := range lines[1:] {
+		want := col
+		if ids[i] == "00g1wider-than-the-first-block" {
+			want = len(ids[i]) + 2
+		}
+		if got := strings.Index(line, " group"); got != want {
+			t.Errorf("line %d = %q, Name column at %d, want %d", i+1, line, got, want)
+		}
+	}
+}
+
+func TestListAppsFromSnapshot(t *testing.T) {
+	snap := &oktaapi.Snapshot{
+		Apps: []oktaapi.App{{ID: "0oa1gjh63g214q0Hq0g4", Name: "testorgone_customsaml20app_1", Label: "Test Custom Saml 2.0 App"}},
+	}
+	if err := listApps(context.Background(), io.Discard, snap, "test"); err != nil {
 		t.Error(err)
 	}
 }
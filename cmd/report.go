@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/flynshue/oktactl/pkg/okta-api/v2/oktaapi"
+)
+
+// userAccess is a user with every group assignment that grants them access to an app.
+type userAccess struct {
+	oktaapi.User
+	Groups []string `json:"groups"`
+	Roles  []string `json:"roles,omitempty"`
+}
+
+// appAccess expands the groups assigned to appID into their members, merging
+// users that are granted access through more than one group. Assigned groups that
+// no longer exist are skipped and returned in unresolved.
+func appAccess(os OktaService, appID string) (app oktaapi.App, access []userAccess, unresolved []string, err error) {
+	app, groups, err := os.ListAppsGroups(appID)
+	if err != nil {
+		return app, nil, nil, err
+	}
+	byID := map[string]*userAccess{}
+	for _, group := range groups {
+		users, err := os.ListOktaGroupUsers(group.GroupID)
+		if oktaapi.IsNotFound(err) {
+			unresolved = append(unresolved, group.GroupID)
+			continue
+		}
+		if err != nil {
+			return app, nil, nil, err
+		}
+		name := group.Name
+		if name == "" {
+			name = group.GroupID
+		}
+		for _, user := range users {
+			ua, ok := byID[user.ID]
+			if !ok {
+				ua = &userAccess{User: user}
+				byID[user.ID] = ua
+			}
+			ua.Groups = appendUnique(ua.Groups, name)
+			ua.Roles = appendUnique(ua.Roles, group.SAMLRoles...)
+			if group.Role != "" {
+				ua.Roles = appendUnique(ua.Roles, group.Role)
+			}
+		}
+	}
+	access = make([]userAccess, 0, len(byID))
+	for _, ua := range byID {
+		access = append(access, *ua)
+	}
+	sort.Slice(access, func(i, j int) bool {
+		if access[i].Email != access[j].Email {
+			return access[i].Email < access[j].Email
+		}
+		return access[i].ID < access[j].ID
+	})
+	return app, access, unresolved, nil
+}
+
+// reportAppAccess writes the access review of an app to w, and a warning to warn for each
+// assigned group that could not be resolved.
+func reportAppAccess(w, warn io.Writer, svc OktaService, appRef, format string) error {
+	appID, err := oktaapi.ResolveAppID(svc, appRef)
+	if err != nil {
+		return err
+	}
+	app, access, unresolved, err := appAccess(svc, appID)
+	if err != nil {
+		return err
+	}
+	for _, groupID := range unresolved {
+		fmt.Fprintf(warn, "warning: group %s is assigned to %s but no longer exists, its members are not listed\n", groupID, app.Label)
+	}
+	return writeAppAccess(w, format, app, access)
+}
+
+func writeAppAccess(w io.Writer, format string, app oktaapi.App, access []userAccess) error {
+	switch format {
+	case "csv":
+		return writeAppAccessCSV(w, access)
+	case "markdown", "md":
+		return writeAppAccessMarkdown(w, app, access)
+	case "html":
+		return appAccessHTML.Execute(w, struct {
+			App    oktaapi.App
+			Access []userAccess
+		}{app, access})
+	default:
+		return fmt.Errorf("unsupported output format %q, must be one of csv, markdown, html", format)
+	}
+}
+
+var appAccessHeader = []string{"User ID", "Login", "Email", "First Name", "Last Name", "Status", "Last Login", "Groups", "Roles"}
+
+func appAccessRow(ua userAccess) []string {
+	return []string{ua.ID, ua.Login, ua.Email, ua.FirstName, ua.LastName, ua.Status, ua.LastLogin,
+		strings.Join(ua.Groups, "; "), strings.Join(ua.Roles, "; ")}
+}
+
+func writeAppAccessCSV(w io.Writer, access []userAccess) error {
+	cw := csv.NewWriter(w)
+	cw.Write(appAccessHeader)
+	for _, ua := range access {
+		cw.Write(appAccessRow(ua))
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeAppAccessMarkdown(w io.Writer, app oktaapi.App, access []userAccess) error {
+	fmt.Fprintf(w, "# Access review for %s (%s)\n\n", app.Label, app.ID)
+	fmt.Fprintf(w, "%d users\n\n", len(access))
+	fmt.Fprintf(w, "| %s |\n", strings.Join(appAccessHeader, " | "))
+	fmt.Fprintf(w, "|%s\n", strings.Repeat(" --- |", len(appAccessHeader)))
+	for _, ua := range access {
+		row := appAccessRow(ua)
+		for i, col := range row {
+			row[i] = strings.ReplaceAll(col, "|", `\|`)
+		}
+		if _, err := fmt.Fprintf(w, "| %s |\n", strings.Join(row, " | ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var appAccessHTML = template.Must(template.New("app-access").Funcs(template.FuncMap{"join": strings.Join}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Access review for {{.App.Label}}</title>
+</head>
+<body>
+<h1>Access review for {{.App.Label}} ({{.App.ID}})</h1>
+<p>{{len .Access}} users</p>
+<table border="1">
+<tr><th>User ID</th><th>Login</th><th>Email</th><th>First Name</th><th>Last Name</th><th>Status</th><th>Last Login</th><th>Groups</th><th>Roles</th></tr>
+{{- range .Access}}
+<tr><td>{{.ID}}</td><td>{{.Login}}</td><td>{{.Email}}</td><td>{{.FirstName}}</td><td>{{.LastName}}</td><td>{{.Status}}</td><td>{{.LastLogin}}</td><td>{{join .Groups "; "}}</td><td>{{join .Roles "; "}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// appendUnique appends each value to s that s does not already contain.
+func appendUnique(s []string, values ...string) []string {
+	for _, v := range values {
+		found := false
+		for _, existing := range s {
+			if existing == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			s = append(s, v)
+		}
+	}
+	return s
+}
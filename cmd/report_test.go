@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/flynshue/oktactl/pkg/fakeokta"
+	"github.com/flynshue/oktactl/pkg/okta-api/v2/oktaapi"
+)
+
+func TestAppAccess(t *testing.T) {
+	_, access, _, err := appAccess(&MockOktaClient{}, "0oa1gjh63g214q0Hq0g4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(access) != 3 {
+		t.Fatalf("got %d users, want 3 deduplicated users", len(access))
+	}
+	for _, ua := range access {
+		if len(ua.Groups) != 3 {
+			t.Errorf("user %s granted by %v, want 3 groups", ua.ID, ua.Groups)
+		}
+		if strings.Join(ua.Roles, ",") != "samlRoles01,samlRoles02,ReadRole" {
+			t.Errorf("user %s roles = %v", ua.ID, ua.Roles)
+		}
+	}
+}
+
+func TestWriteAppAccess(t *testing.T) {
+	app, access, _, err := appAccess(&MockOktaClient{}, "0oa1gjh63g214q0Hq0g4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, format := range []string{"csv", "markdown", "html"} {
+		buf := &bytes.Buffer{}
+		if err := writeAppAccess(buf, format, app, access); err != nil {
+			t.Errorf("%s: %s", format, err)
+		}
+		if !strings.Contains(buf.String(), "user1@example.com") {
+			t.Errorf("%s output missing user1@example.com:\n%s", format, buf.String())
+		}
+	}
+	if err := writeAppAccess(&bytes.Buffer{}, "xml", app, access); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
+
+func TestReportAppAccessDeletedGroup(t *testing.T) {
+	seed, err := fakeokta.LoadSeed("../pkg/fakeokta/testdata/seed.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed.Apps[0].Groups = append(seed.Apps[0].Groups, fakeokta.AppAssignment{ID: "00g1gone000000000009"})
+	srv := fakeokta.New(seed)
+	t.Cleanup(srv.Close)
+	client, err := oktaapi.NewClient(srv.URL, srv.Token, srv.Options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, warn := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := reportAppAccess(out, warn, client, "0oa1aws0000000000001", "csv"); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(out.String(), "\n"); n != 3 {
+		t.Errorf("report has %d lines, want a header and the 2 members of the groups that exist:\n%s", n, out)
+	}
+	if !strings.Contains(warn.String(), "group 00g1gone000000000009 is assigned to AWS Prod but no longer exists") {
+		t.Errorf("warnings = %q", warn)
+	}
+
+	// A snapshot keeps the dangling assignment, and has no members for the group.
+	snap, err := client.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	warn.Reset()
+	if err := reportAppAccess(out, warn, snap, "0oa1aws0000000000001", "csv"); err != nil {
+		t.Fatalf("report from a snapshot: %v", err)
+	}
+	if n := strings.Count(out.String(), "\n"); n != 3 || !strings.Contains(warn.String(), "group 00g1gone000000000009") {
+		t.Errorf("report from a snapshot:\n%s\nwarnings = %q", out, warn)
+	}
+	if err := runAuditStaleUsers(io.Discard, snap, 90, "json"); err != nil {
+		t.Errorf("audit stale-users from a snapshot: %v", err)
+	}
+}
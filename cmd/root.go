@@ -25,7 +25,13 @@ import (
 	"github.com/spf13/viper"
 )
 
-var cfgFile string
+var (
+	cfgFile     string
+	snapshotDir string
+	recordDir   string
+	replayDir   string
+	noCache     bool
+)
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -54,6 +60,10 @@ func init() {
 	// will be global for your application.
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.oktactl.yaml)")
+	rootCmd.PersistentFlags().StringVar(&snapshotDir, "from-snapshot", "", "read from a snapshot directory created by 'oktactl export snapshot' instead of the Okta API")
+	rootCmd.PersistentFlags().StringVar(&recordDir, "record", "", "record Okta API requests and responses, with tokens and personal data redacted, to fixtures in this directory")
+	rootCmd.PersistentFlags().StringVar(&replayDir, "replay", "", "answer Okta API requests from fixtures recorded with --record instead of the Okta API")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "fetch everything from the Okta API, ignoring responses cached by the cache_ttl setting")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
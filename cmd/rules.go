@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// accessRules is the rules file read by `oktactl audit check`.
+type accessRules struct {
+	Rules []accessRule `yaml:"rules"`
+}
+
+// accessRule declares one invariant. Exactly one check is set per rule:
+//
+//	app + allowedGroups   every group assigned to app has a name matching the allowedGroups regex
+//	group + maxMembers    group has at most maxMembers members
+//	group + requiredGroup every member of group is also a member of requiredGroup
+type accessRule struct {
+	Name          string `yaml:"name"`
+	App           string `yaml:"app,omitempty"`
+	AllowedGroups string `yaml:"allowedGroups,omitempty"`
+	Group         string `yaml:"group,omitempty"`
+	MaxMembers    *int   `yaml:"maxMembers,omitempty"`
+	RequiredGroup string `yaml:"requiredGroup,omitempty"`
+
+	allowed *regexp.Regexp
+}
+
+func loadAccessRules(path string) (*accessRules, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rules := &accessRules{}
+	if err := yaml.Unmarshal(b, rules); err != nil {
+		return nil, fmt.Errorf("reading rules %s: %w", path, err)
+	}
+	for i := range rules.Rules {
+		if rules.Rules[i].Name == "" {
+			rules.Rules[i].Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		if err := rules.Rules[i].validate(); err != nil {
+			return nil, fmt.Errorf("rule %d %q: %w", i+1, rules.Rules[i].Name, err)
+		}
+	}
+	return rules, nil
+}
+
+func (r *accessRule) validate() error {
+	checks := 0
+	if r.AllowedGroups != "" {
+		if r.App == "" {
+			return fmt.Errorf("allowedGroups requires app")
+		}
+		re, err := regexp.Compile(r.AllowedGroups)
+		if err != nil {
+			return err
+		}
+		r.allowed = re
+		checks++
+	}
+	if r.MaxMembers != nil {
+		checks++
+	}
+	if r.RequiredGroup != "" {
+		checks++
+	}
+	if (r.MaxMembers != nil || r.RequiredGroup != "") && r.Group == "" {
+		return fmt.Errorf("maxMembers and requiredGroup require group")
+	}
+	if checks != 1 {
+		return fmt.Errorf("must set exactly one of allowedGroups, maxMembers or requiredGroup")
+	}
+	return nil
+}
+
+// evaluate returns a finding for every way the org breaks the rule.
+func (r *accessRule) evaluate(os OktaService) ([]finding, error) {
+	findings := []finding{}
+	switch {
+	case r.allowed != nil:
+		app, groups, err := os.ListAppsGroups(r.App)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range groups {
+			if !r.allowed.MatchString(group.Name) {
+				findings = append(findings, finding{
+					Severity: severityHigh, Check: r.Name, ResourceType: "app", ResourceID: app.ID, ResourceName: app.Label,
+					Message: fmt.Sprintf("assigned to group %s %q which does not match %s", group.GroupID, group.Name, r.AllowedGroups),
+				})
+			}
+		}
+	case r.MaxMembers != nil:
+		users, err := os.ListOktaGroupUsers(r.Group)
+		if err != nil {
+			return nil, err
+		}
+		if len(users) > *r.MaxMembers {
+			findings = append(findings, finding{
+				Severity: severityHigh, Check: r.Name, ResourceType: "group", ResourceID: r.Group,
+				Message: fmt.Sprintf("has %d members, more than the allowed %d", len(users), *r.MaxMembers),
+			})
+		}
+	case r.RequiredGroup != "":
+		users, err := os.ListOktaGroupUsers(r.Group)
+		if err != nil {
+			return nil, err
+		}
+		required, err := os.ListOktaGroupUsers(r.RequiredGroup)
+		if err != nil {
+			return nil, err
+		}
+		inRequired := map[string]bool{}
+		for _, user := range required {
+			inRequired[user.ID] = true
+		}
+		for _, user := range users {
+			if !inRequired[user.ID] {
+				findings = append(findings, finding{
+					Severity: severityHigh, Check: r.Name, ResourceType: "user", ResourceID: user.ID, ResourceName: user.Email,
+					Message: fmt.Sprintf("member of group %s but not of required group %s", r.Group, r.RequiredGroup),
+				})
+			}
+		}
+	}
+	return findings, nil
+}
+
+// checkAccessRules evaluates every rule and returns all violations.
+func checkAccessRules(os OktaService, rules *accessRules) ([]finding, error) {
+	findings := []finding{}
+	for _, rule := range rules.Rules {
+		violations, err := rule.evaluate(os)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+		findings = append(findings, violations...)
+	}
+	return findings, nil
+}
+
+func runAuditCheck(w io.Writer, svc OktaService, path, format string) error {
+	rules, err := loadAccessRules(path)
+	if err != nil {
+		return err
+	}
+	findings, err := checkAccessRules(svc, rules)
+	if err != nil {
+		return err
+	}
+	if err := writeFindings(w, format, findings); err != nil {
+		return err
+	}
+	if len(findings) > 0 {
+		return fmt.Errorf("%d rule violations", len(findings))
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeRules(t *testing.T, rules string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(rules), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCheckAccessRules(t *testing.T) {
+	path := writeRules(t, `
+rules:
+  - name: fake groups only
+    app: 0oa1gjh63g214q0Hq0g4
+    allowedGroups: "^Fake Group 0[12]$"
+  - name: small group
+    group: 00g1emaKYZTWRYYRRTSK
+    maxMembers: 2
+  - group: 00g1emaKYZTWRYYRRTSK
+    requiredGroup: 00gg0xVALADWBPXOFZAS
+`)
+	rules, err := loadAccessRules(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings, err := checkAccessRules(&MockOktaClient{}, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"fake groups only", "small group"}
+	if len(findings) != len(want) {
+		t.Fatalf("got %d violations %v, want %v", len(findings), findings, want)
+	}
+	for i, check := range want {
+		if findings[i].Check != check {
+			t.Errorf("violation %d = %s, want %s", i, findings[i].Check, check)
+		}
+	}
+}
+
+func TestLoadAccessRulesInvalid(t *testing.T) {
+	for name, rules := range map[string]string{
+		"no check":      "rules:\n  - group: 00g1emaKYZTWRYYRRTSK\n",
+		"two checks":    "rules:\n  - group: 00g1emaKYZTWRYYRRTSK\n    maxMembers: 1\n    requiredGroup: 00gg0xVALADWBPXOFZAS\n",
+		"missing app":   "rules:\n  - allowedGroups: '^aws-'\n",
+		"bad regexp":    "rules:\n  - app: 0oa1gjh63g214q0Hq0g4\n    allowedGroups: '('\n",
+		"missing group": "rules:\n  - maxMembers: 1\n",
+	} {
+		if _, err := loadAccessRules(writeRules(t, rules)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/flynshue/oktactl/pkg/eventhook"
+	"github.com/flynshue/oktactl/pkg/notify"
+	"github.com/flynshue/oktactl/pkg/watch"
+	"github.com/spf13/viper"
+)
+
+// eventHookOptions configures `oktactl serve event-hook`.
+type eventHookOptions struct {
+	Listen  string
+	Out     string
+	Notify  bool
+	TLSCert string
+	TLSKey  string
+}
+
+// notifyQueueSize bounds the membership changes waiting to be sent to notification sinks.
+const notifyQueueSize = 256
+
+// notifyQueue sends membership changes to the notification sinks from a single goroutine, so
+// that deliveries are acknowledged without waiting for slow or retrying webhooks, which would
+// make Okta time them out and send them again. A full queue drops changes instead of blocking.
+type notifyQueue struct {
+	n       *notify.Notifier
+	changes chan watch.Event
+	logger  *log.Logger
+}
+
+func newNotifyQueue(n *notify.Notifier, size int, logger *log.Logger) *notifyQueue {
+	return &notifyQueue{n: n, changes: make(chan watch.Event, size), logger: logger}
+}
+
+// run sends queued changes until ctx is done. Changes still queued then are not sent.
+func (q *notifyQueue) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case change := <-q.changes:
+			if err := q.n.Notify(ctx, change); err != nil {
+				q.logger.Printf("notify: %s", err)
+			}
+		}
+	}
+}
+
+// enqueue queues change to be sent, or reports an error if the queue is full.
+func (q *notifyQueue) enqueue(change watch.Event) error {
+	select {
+	case q.changes <- change:
+		return nil
+	default:
+		return fmt.Errorf("notification queue is full, dropped %s %s of %s %s", change.MemberName, change.Action, change.Resource, change.ResourceID)
+	}
+}
+
+// eventHandler writes each event to w as a line of NDJSON and, if q is not nil, queues
+// membership changes for the notification sinks routed to their group or app.
+func eventHandler(w io.Writer, q *notifyQueue) func(context.Context, eventhook.Event) error {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	return func(ctx context.Context, e eventhook.Event) error {
+		mu.Lock()
+		err := enc.Encode(e)
+		mu.Unlock()
+		if err != nil {
+			return err
+		}
+		if q == nil {
+			return nil
+		}
+		if change, ok := e.MembershipChange(); ok {
+			return q.enqueue(change)
+		}
+		return nil
+	}
+}
+
+// runServeEventHook serves the event hook endpoint until interrupted.
+// The shared secret comes from the event_hook_secret config key so that it is not visible in the process list.
+func runServeEventHook(w io.Writer, opts eventHookOptions) error {
+	secret := viper.GetString("event_hook_secret")
+	if secret == "" {
+		return fmt.Errorf("event_hook_secret must be set in the config file or the EVENT_HOOK_SECRET environment variable")
+	}
+	if (opts.TLSCert == "") != (opts.TLSKey == "") {
+		return fmt.Errorf("--tls-cert and --tls-key must be used together")
+	}
+	out := w
+	if opts.Out != "" && opts.Out != "-" {
+		f, err := os.OpenFile(opts.Out, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var q *notifyQueue
+	if opts.Notify {
+		n, err := newNotifier()
+		if err != nil {
+			return err
+		}
+		q = newNotifyQueue(n, notifyQueueSize, logger)
+		go q.run(ctx)
+	}
+	srv := &http.Server{
+		Addr:              opts.Listen,
+		Handler:           &eventhook.Handler{Secret: secret, Handle: eventHandler(out, q), Logger: logger},
+		ReadHeaderTimeout: 10 * time.Second,
+		ErrorLog:          logger,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+	logger.Printf("listening for Okta event hooks on %s", opts.Listen)
+	var err error
+	if opts.TLSCert != "" {
+		err = srv.ListenAndServeTLS(opts.TLSCert, opts.TLSKey)
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flynshue/oktactl/pkg/eventhook"
+	"github.com/flynshue/oktactl/pkg/notify"
+)
+
+func TestEventHandler(t *testing.T) {
+	bodies := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies <- string(b)
+	}))
+	defer srv.Close()
+	n, err := notify.New(notify.Config{
+		Sinks:  []notify.SinkConfig{{Name: "security", Format: notify.FormatSlack, URL: srv.URL}},
+		Routes: []notify.RouteConfig{{Group: "00g1emaKYZTWRYYRRTSK", Sinks: []string{"security"}}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q := newNotifyQueue(n, 1, log.New(io.Discard, "", 0))
+	go q.run(ctx)
+	buf := &bytes.Buffer{}
+	handle := eventHandler(buf, q)
+	events := []eventhook.Event{
+		{UUID: "1", EventType: eventhook.GroupUserRemove, Target: []eventhook.Target{
+			{ID: "00u1", Type: "User", AlternateID: "alex@example.com"},
+			{ID: "00g1emaKYZTWRYYRRTSK", Type: "UserGroup"},
+		}},
+		{UUID: "2", EventType: "user.session.start"},
+	}
+	for _, e := range events {
+		if err := handle(context.Background(), e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 2 || !strings.Contains(lines[0], `"uuid":"1"`) {
+		t.Errorf("output = %s", buf.String())
+	}
+	select {
+	case body := <-bodies:
+		if want := `{"text": "oktactl: alex@example.com was removed from group 00g1emaKYZTWRYYRRTSK"}`; body != want {
+			t.Errorf("notification = %s", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification was sent")
+	}
+}
+
+func TestEventHookAcknowledgesBeforeNotifying(t *testing.T) {
+	release := make(chan struct{})
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { <-release }))
+	defer sink.Close()
+	defer close(release)
+	n, err := notify.New(notify.Config{
+		Sinks:  []notify.SinkConfig{{Name: "security", Format: notify.FormatSlack, URL: sink.URL}},
+		Routes: []notify.RouteConfig{{Group: "00g1emaKYZTWRYYRRTSK", Sinks: []string{"security"}}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logs := &bytes.Buffer{}
+	q := newNotifyQueue(n, 1, log.New(io.Discard, "", 0))
+	go q.run(ctx)
+	hook := &eventhook.Handler{Secret: "secret", Handle: eventHandler(io.Discard, q), Logger: log.New(logs, "", 0)}
+	event := `{"uuid": "%d", "eventType": "group.user_membership.add", "target": [{"id": "00u1", "type": "User", "alternateId": "alex@example.com"}, {"id": "00g1emaKYZTWRYYRRTSK", "type": "UserGroup"}]}`
+	// The first change is being sent to the sink, which does not answer, the second waits in
+	// the queue, and the third does not fit.
+	for i := 1; i <= 3; i++ {
+		body := fmt.Sprintf(`{"data": {"events": [`+event+`]}}`, i)
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Authorization", "secret")
+		rec := httptest.NewRecorder()
+		done := make(chan struct{})
+		go func() { hook.ServeHTTP(rec, req); close(done) }()
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("delivery %d was not acknowledged while the sink was slow", i)
+		}
+		if rec.Code != http.StatusOK {
+			t.Errorf("delivery %d: status %d", i, rec.Code)
+		}
+		if i == 1 {
+			// Let the worker take the first change off the queue.
+			for len(q.changes) > 0 {
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}
+	if !strings.Contains(logs.String(), "notification queue is full") {
+		t.Errorf("dropped notification was not logged: %q", logs)
+	}
+}
@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/flynshue/oktactl/pkg/okta-api/v2/oktaapi"
+	"gopkg.in/yaml.v3"
+)
+
+// sodConfig is the separation-of-duties file read by `oktactl audit sod`.
+type sodConfig struct {
+	Conflicts []sodConflict `yaml:"conflicts"`
+}
+
+// sodConflict is a toxic combination: no user may hold both A and B.
+type sodConflict struct {
+	Name string      `yaml:"name"`
+	A    entitlement `yaml:"a"`
+	B    entitlement `yaml:"b"`
+}
+
+// entitlement is membership of a group or access to an app. Exactly one of Group or App is set.
+type entitlement struct {
+	Group string `yaml:"group,omitempty"`
+	App   string `yaml:"app,omitempty"`
+}
+
+func (e entitlement) String() string {
+	if e.App != "" {
+		return "app " + e.App
+	}
+	return "group " + e.Group
+}
+
+// holder is a user that holds an entitlement and every path that grants it.
+type holder struct {
+	User  oktaapi.User
+	Paths []string
+}
+
+// sodViolation is a user that holds both sides of a conflict.
+type sodViolation struct {
+	Conflict string       `json:"conflict"`
+	User     oktaapi.User `json:"user"`
+	APaths   []string     `json:"aPaths"`
+	BPaths   []string     `json:"bPaths"`
+}
+
+func loadSodConfig(path string) (*sodConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &sodConfig{}
+	if err := yaml.Unmarshal(b, config); err != nil {
+		return nil, fmt.Errorf("reading sod config %s: %w", path, err)
+	}
+	for i, c := range config.Conflicts {
+		if c.Name == "" {
+			config.Conflicts[i].Name = fmt.Sprintf("conflict-%d", i+1)
+		}
+		for _, e := range []entitlement{c.A, c.B} {
+			if (e.Group == "") == (e.App == "") {
+				return nil, fmt.Errorf("conflict %d %q: a and b must each set exactly one of group or app", i+1, config.Conflicts[i].Name)
+			}
+		}
+	}
+	return config, nil
+}
+
+// holders returns every user that holds e keyed by user ID. App access counts
+// both direct assignments and membership of any group assigned to the app. Groups that
+// no longer exist are held by nobody, with a warning written to warn.
+func holders(os OktaService, e entitlement, warn io.Writer) (map[string]*holder, error) {
+	held := map[string]*holder{}
+	add := func(user oktaapi.User, path string) {
+		h, ok := held[user.ID]
+		if !ok {
+			h = &holder{User: user}
+			held[user.ID] = h
+		}
+		h.Paths = appendUnique(h.Paths, path)
+	}
+	if e.Group != "" {
+		users, err := os.ListOktaGroupUsers(e.Group)
+		if oktaapi.IsNotFound(err) {
+			fmt.Fprintf(warn, "warning: group %s no longer exists, nobody holds it\n", e.Group)
+			return held, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			add(user, fmt.Sprintf("member of group %s", e.Group))
+		}
+		return held, nil
+	}
+	app, groups, err := os.ListAppsGroups(e.App)
+	if err != nil {
+		return nil, err
+	}
+	appUsers, err := os.ListAppUsers(e.App)
+	if err != nil {
+		return nil, err
+	}
+	for _, au := range appUsers {
+		if au.Scope != "USER" {
+			continue
+		}
+		user := au.Embedded.User
+		if user.ID == "" {
+			user.ID = au.ID
+		}
+		add(user, fmt.Sprintf("assigned directly to app %s", app.Label))
+	}
+	for _, group := range groups {
+		users, err := os.ListOktaGroupUsers(group.GroupID)
+		if oktaapi.IsNotFound(err) {
+			fmt.Fprintf(warn, "warning: group %s is assigned to %s but no longer exists, its members are not checked\n", group.GroupID, app.Label)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := group.Name
+		if name == "" {
+			name = group.GroupID
+		}
+		for _, user := range users {
+			add(user, fmt.Sprintf("app %s via group %s", app.Label, name))
+		}
+	}
+	return held, nil
+}
+
+// auditSod reports every user that holds both sides of a conflict, writing warnings about
+// groups that no longer exist to warn.
+func auditSod(os OktaService, config *sodConfig, warn io.Writer) ([]sodViolation, error) {
+	cache := map[entitlement]map[string]*holder{}
+	lookup := func(e entitlement) (map[string]*holder, error) {
+		if held, ok := cache[e]; ok {
+			return held, nil
+		}
+		held, err := holders(os, e, warn)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e, err)
+		}
+		cache[e] = held
+		return held, nil
+	}
+	violations := []sodViolation{}
+	for _, c := range config.Conflicts {
+		a, err := lookup(c.A)
+		if err != nil {
+			return nil, err
+		}
+		b, err := lookup(c.B)
+		if err != nil {
+			return nil, err
+		}
+		conflicting := []sodViolation{}
+		for id, ha := range a {
+			if hb, ok := b[id]; ok {
+				conflicting = append(conflicting, sodViolation{Conflict: c.Name, User: ha.User, APaths: ha.Paths, BPaths: hb.Paths})
+			}
+		}
+		sort.Slice(conflicting, func(i, j int) bool { return conflicting[i].User.ID < conflicting[j].User.ID })
+		violations = append(violations, conflicting...)
+	}
+	return violations, nil
+}
+
+func runAuditSod(w, warn io.Writer, svc OktaService, path, format string) error {
+	config, err := loadSodConfig(path)
+	if err != nil {
+		return err
+	}
+	violations, err := auditSod(svc, config, warn)
+	if err != nil {
+		return err
+	}
+	if err := writeSodViolations(w, format, violations); err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("%d separation of duties conflicts", len(violations))
+	}
+	return nil
+}
+
+func writeSodViolations(w io.Writer, format string, violations []sodViolation) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(violations)
+	case "table":
+		if len(violations) == 0 {
+			fmt.Fprintln(w, "no conflicts found")
+			return nil
+		}
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.TabIndent)
+		fmt.Fprintln(tw, "Conflict\t Okta User ID\t Email\t Holds A\t Holds B\t")
+		for _, v := range violations {
+			fmt.Fprintf(tw, "%s\t %s\t %s\t %s\t %s\t\n", v.Conflict, v.User.ID, v.User.Email, strings.Join(v.APaths, "; "), strings.Join(v.BPaths, "; "))
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unsupported output format %q, must be one of table, json", format)
+	}
+}
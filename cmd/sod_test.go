@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flynshue/oktactl/pkg/fakeokta"
+	"github.com/flynshue/oktactl/pkg/okta-api/v2/oktaapi"
+)
+
+func TestAuditSod(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sod.yaml")
+	config := `
+conflicts:
+  - name: approver and app
+    a:
+      group: 00gbkkGFFWZDLCNTAGQR
+    b:
+      app: 0oa1gjh63g214q0Hq0g4
+`
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sod, err := loadSodConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	violations, err := auditSod(&MockOktaClient{}, sod, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 3 {
+		t.Fatalf("got %d violations, want 3", len(violations))
+	}
+	for _, v := range violations {
+		if len(v.BPaths) != 3 {
+			t.Errorf("user %s app paths = %v, want 3 group paths", v.User.ID, v.BPaths)
+		}
+	}
+	if err := writeSodViolations(&bytes.Buffer{}, "table", violations); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHoldersDirectAssignment(t *testing.T) {
+	held, err := holders(&MockOktaClient{}, entitlement{App: "0oa1gjh63g214q0Hq0g4"}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	direct, ok := held["00u1hqieohhlPBv581d8"]
+	if !ok {
+		t.Fatal("directly assigned user missing from holders")
+	}
+	if len(direct.Paths) != 1 || direct.Paths[0] != "assigned directly to app Test Custom Saml 2.0 App" {
+		t.Errorf("direct paths = %v", direct.Paths)
+	}
+	if len(held) != 4 {
+		t.Errorf("got %d holders, want 4", len(held))
+	}
+}
+
+func TestAuditSodDeletedGroups(t *testing.T) {
+	seed, err := fakeokta.LoadSeed("../pkg/fakeokta/testdata/seed.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed.Apps[0].Groups = append(seed.Apps[0].Groups, fakeokta.AppAssignment{ID: "00g1gone000000000009"})
+	srv := fakeokta.New(seed)
+	t.Cleanup(srv.Close)
+	client, err := oktaapi.NewClient(srv.URL, srv.Token, srv.Options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &sodConfig{Conflicts: []sodConflict{
+		{Name: "admins and aws", A: entitlement{Group: "00g1admins0000000001"}, B: entitlement{App: "0oa1aws0000000000001"}},
+		{Name: "deleted and wiki", A: entitlement{Group: "00g1gone000000000008"}, B: entitlement{App: "0oa1wiki000000000002"}},
+	}}
+	warn := &bytes.Buffer{}
+	violations, err := auditSod(client, config, warn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 1 || violations[0].User.ID != "00u1alex000000000001" {
+		t.Errorf("violations = %+v, want alex holding both admins and aws", violations)
+	}
+	for _, want := range []string{
+		"group 00g1gone000000000009 is assigned to AWS Prod but no longer exists",
+		"group 00g1gone000000000008 no longer exists",
+	} {
+		if !strings.Contains(warn.String(), want) {
+			t.Errorf("warnings %q do not mention %q", warn, want)
+		}
+	}
+}
+
+func TestLoadSodConfigInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sod.yaml")
+	config := "conflicts:\n  - a:\n      group: 00gbkkGFFWZDLCNTAGQR\n      app: 0oa1gjh63g214q0Hq0g4\n    b:\n      group: 00gg0xVALADWBPXOFZAS\n"
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadSodConfig(path); err == nil {
+		t.Error("expected error for entitlement with both group and app")
+	}
+}
@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/flynshue/oktactl/pkg/okta-api/v2/oktaapi"
+)
+
+// globMatcher compiles a glob of *, ? and [classes] into a regexp matching whole names. Unlike
+// path.Match, * also matches /, which group names often contain, as in "eng/aws-admins".
+func globMatcher(pattern string) (*regexp.Regexp, error) {
+	var re strings.Builder
+	re.WriteString("(?s)^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			re.WriteString(".*")
+		case '?':
+			re.WriteString(".")
+		case '\\':
+			if i+1 == len(pattern) {
+				return nil, fmt.Errorf("trailing \\")
+			}
+			i++
+			re.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated [")
+			}
+			re.WriteString(pattern[i : i+end+2])
+			i += end + 1
+		default:
+			re.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	re.WriteString("$")
+	return regexp.Compile(re.String())
+}
+
+var unsafeResourceChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// resourceNames hands out unique Terraform resource names derived from Okta names.
+type resourceNames map[string]bool
+
+func (n resourceNames) name(s string) string {
+	base := strings.Trim(unsafeResourceChars.ReplaceAllString(strings.ToLower(s), "_"), "_")
+	if base == "" || (base[0] >= '0' && base[0] <= '9') {
+		base = "r_" + base
+	}
+	name := base
+	for i := 2; n[name]; i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	n[name] = true
+	return name
+}
+
+type terraformGroup struct {
+	Group    oktaapi.Group
+	Resource string
+	Members  []oktaapi.User
+}
+
+type terraformApp struct {
+	App         oktaapi.App
+	Resource    string
+	Assignments []oktaapi.GroupAssignmentResp
+}
+
+// exportTerraform writes okta_group, okta_group_memberships and okta_app_group_assignments
+// resources with matching import blocks for every Okta group whose name matches pattern.
+// okta_app_group_assignments is authoritative for an app, so apps assigned to a matching group
+// keep all of their assignments; groups outside the pattern are referenced by ID.
+func exportTerraform(os OktaService, pattern string, w io.Writer) error {
+	match, err := globMatcher(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid group pattern %q: %w", pattern, err)
+	}
+	allGroups, err := os.ListOktaGroups("")
+	if err != nil {
+		return err
+	}
+	groupNames := resourceNames{}
+	groups := []terraformGroup{}
+	byID := map[string]terraformGroup{}
+	for _, group := range allGroups {
+		if group.Type != "" && group.Type != "OKTA_GROUP" {
+			continue
+		}
+		if !match.MatchString(group.Name) {
+			continue
+		}
+		users, err := os.ListOktaGroupUsers(group.ID)
+		if err != nil {
+			return err
+		}
+		tg := terraformGroup{Group: group, Members: users}
+		groups = append(groups, tg)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Group.Name < groups[j].Group.Name })
+	for i := range groups {
+		groups[i].Resource = groupNames.name(groups[i].Group.Name)
+		byID[groups[i].Group.ID] = groups[i]
+	}
+
+	apps, err := os.ListApps("")
+	if err != nil {
+		return err
+	}
+	sort.Slice(apps, func(i, j int) bool { return apps[i].Label < apps[j].Label })
+	appNames := resourceNames{}
+	assigned := []terraformApp{}
+	for _, app := range apps {
+		_, assignments, err := os.ListAppsGroups(app.ID)
+		if err != nil {
+			return err
+		}
+		for _, assignment := range assignments {
+			if _, ok := byID[assignment.GroupID]; ok {
+				assigned = append(assigned, terraformApp{App: app, Resource: appNames.name(app.Label), Assignments: assignments})
+				break
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "# Generated by oktactl export terraform --groups %q\n", pattern)
+	for _, g := range groups {
+		fmt.Fprintf(w, "\nresource \"okta_group\" %q {\n", g.Resource)
+		fmt.Fprintf(w, "  name        = %s\n", strconv.Quote(g.Group.Name))
+		if g.Group.Description != "" {
+			fmt.Fprintf(w, "  description = %s\n", strconv.Quote(g.Group.Description))
+		}
+		fmt.Fprintln(w, "}")
+
+		fmt.Fprintf(w, "\nresource \"okta_group_memberships\" %q {\n", g.Resource)
+		fmt.Fprintf(w, "  group_id = okta_group.%s.id\n", g.Resource)
+		fmt.Fprintln(w, "  users = [")
+		for _, user := range g.Members {
+			fmt.Fprintf(w, "    %q, # %s\n", user.ID, userLabel(user))
+		}
+		fmt.Fprintln(w, "  ]")
+		fmt.Fprintln(w, "}")
+	}
+	for _, a := range assigned {
+		fmt.Fprintf(w, "\nresource \"okta_app_group_assignments\" %q {\n", a.Resource)
+		fmt.Fprintf(w, "  app_id = %q # %s\n", a.App.ID, a.App.Label)
+		for _, assignment := range a.Assignments {
+			fmt.Fprintln(w, "\n  group {")
+			if g, ok := byID[assignment.GroupID]; ok {
+				fmt.Fprintf(w, "    id       = okta_group.%s.id\n", g.Resource)
+			} else {
+				fmt.Fprintf(w, "    id       = %q # %s\n", assignment.GroupID, assignment.Name)
+			}
+			fmt.Fprintf(w, "    priority = %d\n", assignment.Priority)
+			profile := map[string]interface{}{}
+			if len(assignment.SAMLRoles) > 0 {
+				profile["samlRoles"] = assignment.SAMLRoles
+			}
+			if assignment.Role != "" {
+				profile["role"] = assignment.Role
+			}
+			if len(profile) > 0 {
+				b, err := json.Marshal(profile)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(w, "    profile  = jsonencode(%s)\n", b)
+			}
+			fmt.Fprintln(w, "  }")
+		}
+		fmt.Fprintln(w, "}")
+	}
+
+	for _, g := range groups {
+		writeImport(w, "okta_group."+g.Resource, g.Group.ID)
+		writeImport(w, "okta_group_memberships."+g.Resource, g.Group.ID)
+	}
+	for _, a := range assigned {
+		writeImport(w, "okta_app_group_assignments."+a.Resource, a.App.ID)
+	}
+	return nil
+}
+
+func writeImport(w io.Writer, to, id string) {
+	fmt.Fprintf(w, "\nimport {\n  to = %s\n  id = %q\n}\n", to, id)
+}
+
+func runExportTerraform(w io.Writer, svc OktaService, pattern, out string) error {
+	if out == "" {
+		return exportTerraform(svc, pattern, w)
+	}
+	// Write next to out and rename, so that a failed export never leaves a partial file behind.
+	f, err := os.CreateTemp(filepath.Dir(out), ".oktactl-terraform-*")
+	if err != nil {
+		return err
+	}
+	if err := exportTerraform(svc, pattern, f); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), out)
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flynshue/oktactl/pkg/okta-api/v2/oktaapi"
+)
+
+func TestExportTerraform(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := exportTerraform(&MockOktaClient{}, "Fake Group 0[12]", buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"resource \"okta_group\" \"fake_group_01\" {\n  name        = \"Fake Group 01\"\n}",
+		"resource \"okta_group_memberships\" \"fake_group_02\" {\n  group_id = okta_group.fake_group_02.id\n",
+		"    \"00gg0xVALADWBPXOFZAS\", # user1@example.com (00gg0xVALADWBPXOFZAS)\n",
+		"resource \"okta_app_group_assignments\" \"test_custom_saml_2_0_app\" {\n  app_id = \"0oa1gjh63g214q0Hq0g4\" # Test Custom Saml 2.0 App\n",
+		"    id       = \"00gg0xVALADWBPXOFZAK\" # Fake Group 03\n",
+		"    profile  = jsonencode({\"role\":\"ReadRole\",\"samlRoles\":[\"samlRoles01\",\"samlRoles02\"]})\n",
+		"import {\n  to = okta_group.fake_group_01\n  id = \"00g1emaKYZTWRYYRRTSK\"\n}",
+		"import {\n  to = okta_app_group_assignments.test_sample_plugin_app\n  id = \"0oabkvBLDEKCNXBGYUAS\"\n}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "resource \"okta_group\" \"fake_group_03\"") {
+		t.Error("group outside the pattern was exported")
+	}
+}
+
+func TestGlobMatcher(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"aws-*", "aws-admins", true},
+		{"eng/*", "eng/aws/admins", true},
+		{"*admins", "eng/aws-admins", true},
+		{"aws-?", "aws-1", true},
+		{"Fake Group 0[12]", "Fake Group 03", false},
+		{"Fake Group 0[^12]", "Fake Group 03", true},
+		{"a.b", "axb", false},
+		{`\*`, "*", true},
+		{"aws-*", "old aws-admins", false},
+	} {
+		match, err := globMatcher(tc.pattern)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.pattern, err)
+		}
+		if got := match.MatchString(tc.name); got != tc.want {
+			t.Errorf("%s matches %s = %v, want %v", tc.pattern, tc.name, got, tc.want)
+		}
+	}
+	for _, bad := range []string{"[abc", `abc\`} {
+		if _, err := globMatcher(bad); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+}
+
+// failingGroups fails listing the members of a group after the export has started.
+type failingGroups struct{ MockOktaClient }
+
+func (f *failingGroups) ListOktaGroupUsers(groupID string) ([]oktaapi.User, error) {
+	return nil, errors.New("connection reset")
+}
+
+func TestRunExportTerraformLeavesNoPartialFile(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "okta.tf")
+	if err := os.WriteFile(out, []byte("# previous export\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runExportTerraform(&bytes.Buffer{}, &failingGroups{}, "*", out); err == nil {
+		t.Fatal("expected error")
+	}
+	if b, _ := os.ReadFile(out); string(b) != "# previous export\n" {
+		t.Errorf("out after a failed export = %q, want the previous file", b)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("files left in the directory: %v", entries)
+	}
+	if err := runExportTerraform(&bytes.Buffer{}, &MockOktaClient{}, "*", out); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(out); !strings.HasPrefix(string(b), "# Generated by oktactl export terraform") {
+		t.Errorf("out = %q", b)
+	}
+}
+
+func TestResourceNames(t *testing.T) {
+	names := resourceNames{}
+	for _, tc := range []struct{ in, want string }{
+		{"aws-admins", "aws_admins"},
+		{"AWS Admins", "aws_admins_2"},
+		{"123 team", "r_123_team"},
+	} {
+		if got := names.name(tc.in); got != tc.want {
+			t.Errorf("name(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
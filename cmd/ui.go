@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/flynshue/oktactl/pkg/browse"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const uiHelp = "[yellow]enter[-] open  [yellow]backspace[-] back  [yellow]/[-] search  [yellow]tab[-] apps/groups  [yellow]c[-] copy ID  [yellow]e[-]/[yellow]E[-] export csv/json  [yellow]q[-] quit"
+
+// browserUI is the terminal UI of `oktactl ui`. Views are fetched off the UI goroutine and
+// shown with QueueUpdateDraw; seq numbers each request so that only the latest is shown.
+type browserUI struct {
+	app     *tview.Application
+	screen  tcell.Screen
+	browser *browse.Browser
+	kind    browse.Kind
+	search  *tview.InputField
+	table   *tview.Table
+	status  *tview.TextView
+
+	// searchDelay is how long typing must pause before a search is sent, so that typing a
+	// name does not send a request per key.
+	searchDelay time.Duration
+	exportDir   string
+	now         func() time.Time
+
+	seq   int
+	timer *time.Timer
+}
+
+func newBrowserUI(src browse.Source, screen tcell.Screen) *browserUI {
+	u := &browserUI{
+		app:         tview.NewApplication(),
+		screen:      screen,
+		browser:     browse.New(src),
+		kind:        browse.App,
+		search:      tview.NewInputField(),
+		table:       tview.NewTable(),
+		status:      tview.NewTextView(),
+		searchDelay: 300 * time.Millisecond,
+		exportDir:   ".",
+		now:         time.Now,
+	}
+	u.app.SetScreen(screen)
+	u.search.SetLabel(u.searchLabel()).
+		SetFieldBackgroundColor(tcell.ColorDefault).
+		SetChangedFunc(func(text string) { u.scheduleSearch(text, u.searchDelay) }).
+		SetInputCapture(u.searchKey)
+	u.table.SetSelectable(true, false).
+		SetFixed(1, 0).
+		SetInputCapture(u.tableKey)
+	u.status.SetDynamicColors(true)
+	u.showStatus("")
+	root := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(u.search, 1, 0, true).
+		AddItem(u.table, 0, 1, false).
+		AddItem(u.status, 2, 0, false)
+	u.app.SetRoot(root, true)
+	return u
+}
+
+// run shows every app and runs the UI until it is quit.
+func (u *browserUI) run() error {
+	u.scheduleSearch("", 0)
+	return u.app.Run()
+}
+
+func (u *browserUI) searchLabel() string {
+	return fmt.Sprintf("Search %ss: ", u.kind)
+}
+
+// scheduleSearch searches for query once delay passes without another call.
+func (u *browserUI) scheduleSearch(query string, delay time.Duration) {
+	if u.timer != nil {
+		u.timer.Stop()
+	}
+	u.seq++
+	seq, kind := u.seq, u.kind
+	u.timer = time.AfterFunc(delay, func() {
+		v, err := u.browser.Search(kind, query)
+		u.app.QueueUpdateDraw(func() {
+			if seq != u.seq {
+				return
+			}
+			if err != nil {
+				u.showStatus("[red]" + tview.Escape(err.Error()))
+				return
+			}
+			u.browser.Reset(v)
+			u.show(v)
+		})
+	})
+}
+
+// open drills down into the selected row.
+func (u *browserUI) open() {
+	row, ok := u.selected()
+	if !ok {
+		return
+	}
+	u.seq++
+	seq := u.seq
+	u.showStatus("loading " + tview.Escape(row.Name) + "...")
+	go func() {
+		v, err := u.browser.Open(row)
+		u.app.QueueUpdateDraw(func() {
+			if seq != u.seq {
+				return
+			}
+			if err != nil {
+				u.showStatus("[red]" + tview.Escape(err.Error()))
+				return
+			}
+			u.browser.Push(v)
+			u.show(v)
+		})
+	}()
+}
+
+func (u *browserUI) back() {
+	u.seq++
+	if u.browser.Back() {
+		u.show(u.browser.Current())
+	}
+}
+
+func (u *browserUI) toggleKind() {
+	if u.kind == browse.App {
+		u.kind = browse.Group
+	} else {
+		u.kind = browse.App
+	}
+	u.search.SetLabel(u.searchLabel())
+	u.scheduleSearch(u.search.GetText(), 0)
+}
+
+func (u *browserUI) selected() (browse.Row, bool) {
+	v := u.browser.Current()
+	r, _ := u.table.GetSelection()
+	if v == nil || r < 1 || r > len(v.Rows) {
+		return browse.Row{}, false
+	}
+	return v.Rows[r-1], true
+}
+
+// copyID puts the selected ID on the clipboard. Terminals that support OSC 52 pass it on to
+// the system clipboard, including over SSH.
+func (u *browserUI) copyID() {
+	row, ok := u.selected()
+	if !ok {
+		return
+	}
+	u.screen.SetClipboard([]byte(row.ID))
+	u.showStatus("copied " + row.ID)
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// export writes the current view to a file named after it in exportDir.
+func (u *browserUI) export(format string) {
+	v := u.browser.Current()
+	if v == nil {
+		return
+	}
+	name := strings.Trim(unsafeFileChars.ReplaceAllString(strings.ToLower(v.Title), "-"), "-")
+	path := filepath.Join(u.exportDir, fmt.Sprintf("oktactl-%s-%s.%s", name, u.now().Format("20060102T150405"), format))
+	err := writeExport(path, v, format)
+	if err != nil {
+		u.showStatus("[red]" + tview.Escape(err.Error()))
+		return
+	}
+	u.showStatus("exported " + tview.Escape(path))
+}
+
+func writeExport(path string, v *browse.View, format string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := v.Export(f, format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (u *browserUI) show(v *browse.View) {
+	u.table.Clear()
+	for c, col := range v.Columns {
+		u.table.SetCell(0, c, tview.NewTableCell(col).SetTextColor(tcell.ColorYellow).SetSelectable(false))
+	}
+	for r, row := range v.Rows {
+		for c, cell := range row.Cells {
+			u.table.SetCell(r+1, c, tview.NewTableCell(tview.Escape(cell)).SetExpansion(1))
+		}
+	}
+	u.table.Select(1, 0).ScrollToBeginning()
+	u.showStatus(fmt.Sprintf("%d rows", len(v.Rows)))
+}
+
+// showStatus shows the breadcrumb with msg, and the key help.
+func (u *browserUI) showStatus(msg string) {
+	line := tview.Escape(u.browser.Breadcrumb())
+	if msg != "" {
+		line += "  [gray]" + msg + "[-]"
+	}
+	u.status.SetText(line + "\n" + uiHelp)
+}
+
+func (u *browserUI) searchKey(ev *tcell.EventKey) *tcell.EventKey {
+	switch ev.Key() {
+	case tcell.KeyTab:
+		u.toggleKind()
+		return nil
+	case tcell.KeyEnter, tcell.KeyDown, tcell.KeyEsc:
+		u.app.SetFocus(u.table)
+		return nil
+	}
+	return ev
+}
+
+func (u *browserUI) tableKey(ev *tcell.EventKey) *tcell.EventKey {
+	switch ev.Key() {
+	case tcell.KeyEnter, tcell.KeyRight:
+		u.open()
+		return nil
+	case tcell.KeyBackspace, tcell.KeyBackspace2, tcell.KeyEsc, tcell.KeyLeft:
+		u.back()
+		return nil
+	case tcell.KeyTab:
+		u.toggleKind()
+		return nil
+	case tcell.KeyRune:
+		switch ev.Rune() {
+		case '/':
+			u.app.SetFocus(u.search)
+		case 'c':
+			u.copyID()
+		case 'e':
+			u.export("csv")
+		case 'E':
+			u.export("json")
+		case 'q':
+			u.app.Stop()
+		default:
+			return ev
+		}
+		return nil
+	}
+	return ev
+}
+
+func runUI(src browse.Source) error {
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		return err
+	}
+	return newBrowserUI(src, screen).run()
+}
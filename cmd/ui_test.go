@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// uiState reads from the UI on its own goroutine, since the UI changes state there.
+func uiState[T any](u *browserUI, read func() T) T {
+	ch := make(chan T, 1)
+	u.app.QueueUpdate(func() { ch <- read() })
+	return <-ch
+}
+
+// waitFor polls the UI until cond holds.
+func waitFor(t *testing.T, u *browserUI, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !uiState(u, cond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s, at %q", what, uiState(u, u.browser.Breadcrumb))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBrowserUI(t *testing.T) {
+	client, _ := newFakeOrg(t)
+	screen := tcell.NewSimulationScreen("UTF-8")
+	u := newBrowserUI(client, screen)
+	screen.SetSize(120, 30)
+	u.searchDelay = 0
+	u.exportDir = t.TempDir()
+	u.now = func() time.Time { return time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC) }
+	done := make(chan error, 1)
+	go func() { done <- u.run() }()
+	title := func(want string) func() bool {
+		return func() bool { v := u.browser.Current(); return v != nil && v.Title == want }
+	}
+	waitFor(t, u, "all apps", title(`Apps matching ""`))
+
+	screen.InjectKey(tcell.KeyRune, 'w', tcell.ModNone)
+	screen.InjectKey(tcell.KeyRune, 'i', tcell.ModNone)
+	waitFor(t, u, "the search", title(`Apps matching "wi"`))
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	waitFor(t, u, "the app's groups", title("Groups assigned to Wiki"))
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	waitFor(t, u, "the group's members", title("Members of Everyone"))
+	if got := uiState(u, u.browser.Breadcrumb); got != `Apps matching "wi" > Groups assigned to Wiki > Members of Everyone` {
+		t.Errorf("breadcrumb = %s", got)
+	}
+
+	screen.InjectKey(tcell.KeyRune, 'c', tcell.ModNone)
+	waitFor(t, u, "the copy", func() bool { return len(screen.GetClipboardData()) > 0 })
+	if got := string(screen.GetClipboardData()); !strings.HasPrefix(got, "00u") {
+		t.Errorf("copied %q, want a user ID", got)
+	}
+
+	screen.InjectKey(tcell.KeyRune, 'e', tcell.ModNone)
+	path := filepath.Join(u.exportDir, "oktactl-members-of-everyone-20240301T100000.csv")
+	waitFor(t, u, "the export", func() bool { _, err := os.Stat(path); return err == nil })
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(b), "ID,Login,Name,Email,Status\n") || strings.Count(string(b), "\n") != 4 {
+		t.Errorf("export:\n%s", b)
+	}
+
+	screen.InjectKey(tcell.KeyBackspace2, 0, tcell.ModNone)
+	waitFor(t, u, "going back", title("Groups assigned to Wiki"))
+	screen.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	waitFor(t, u, "the group search", title(`Groups matching "wi"`))
+
+	screen.InjectKey(tcell.KeyRune, 'q', tcell.ModNone)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ui did not quit")
+	}
+}
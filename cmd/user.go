@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/flynshue/oktactl/pkg/okta-api/v2/oktaapi"
+)
+
+// skippedGroup is a group left out of a user operation because its membership is not managed directly.
+type skippedGroup struct {
+	Group  oktaapi.Group `json:"group"`
+	Reason string        `json:"reason"`
+}
+
+// directGroupReason explains why a user's membership of group cannot be changed directly,
+// or returns an empty string if it can.
+func directGroupReason(group oktaapi.Group, ruleManaged map[string]bool) string {
+	switch {
+	case group.Type != "" && group.Type != "OKTA_GROUP":
+		return fmt.Sprintf("%s group membership is managed by Okta", group.Type)
+	case ruleManaged[group.ID]:
+		return "membership is managed by a group rule"
+	}
+	return ""
+}
+
+type mirrorPlan struct {
+	Source  oktaapi.User
+	Target  oktaapi.User
+	Changes []membershipChange
+	Skipped []skippedGroup
+}
+
+// planMirror finds the groups source is directly a member of that target is not.
+// Built-in, app and rule-managed groups are skipped.
+func planMirror(admin OktaGroupAdmin, sourceRef, targetRef string) (*mirrorPlan, error) {
+	source, err := oktaapi.ResolveUser(admin, sourceRef)
+	if err != nil {
+		return nil, fmt.Errorf("source user %s: %w", sourceRef, err)
+	}
+	target, err := oktaapi.ResolveUser(admin, targetRef)
+	if err != nil {
+		return nil, fmt.Errorf("target user %s: %w", targetRef, err)
+	}
+	sourceGroups, err := admin.ListOktaUserGroups(source.ID)
+	if err != nil {
+		return nil, err
+	}
+	targetGroups, err := admin.ListOktaUserGroups(target.ID)
+	if err != nil {
+		return nil, err
+	}
+	ruleManaged, err := ruleManagedGroups(admin)
+	if err != nil {
+		return nil, err
+	}
+	hasGroup := map[string]bool{}
+	for _, group := range targetGroups {
+		hasGroup[group.ID] = true
+	}
+	plan := &mirrorPlan{Source: source, Target: target, Changes: []membershipChange{}}
+	for _, group := range sourceGroups {
+		if hasGroup[group.ID] {
+			continue
+		}
+		if reason := directGroupReason(group, ruleManaged); reason != "" {
+			plan.Skipped = append(plan.Skipped, skippedGroup{Group: group, Reason: reason})
+			continue
+		}
+		plan.Changes = append(plan.Changes, membershipChange{Action: changeAdd, GroupID: group.ID, GroupName: group.Name, User: target})
+	}
+	return plan, nil
+}
+
+func writeMirrorPlan(w io.Writer, plan *mirrorPlan) {
+	fmt.Fprintf(w, "Mirroring group membership of %s to %s\n\n", userLabel(plan.Source), userLabel(plan.Target))
+	for _, s := range plan.Skipped {
+		fmt.Fprintf(w, "skipping group %s: %s\n", desiredGroup{ID: s.Group.ID, Name: s.Group.Name}.label(), s.Reason)
+	}
+	if len(plan.Skipped) > 0 {
+		fmt.Fprintln(w)
+	}
+	writePlan(w, plan.Changes)
+}
+
+func runUserMirror(r io.Reader, w io.Writer, admin OktaGroupAdmin, sourceRef, targetRef string, autoApprove bool) error {
+	plan, err := planMirror(admin, sourceRef, targetRef)
+	if err != nil {
+		return err
+	}
+	writeMirrorPlan(w, plan)
+	if len(plan.Changes) == 0 {
+		return nil
+	}
+	if !autoApprove && !confirm(r, w, fmt.Sprintf("Add %s to these groups?", userLabel(plan.Target))) {
+		return fmt.Errorf("mirror cancelled")
+	}
+	return applyMembership(admin, plan.Changes, w)
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPlanMirror(t *testing.T) {
+	client := &MockOktaClient{}
+	plan, err := planMirror(client, "source@example.com", "target@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Changes) != 1 || plan.Changes[0].GroupID != "00gg0xVALADWBPXOFZAK" || plan.Changes[0].User.ID != "00utarget" {
+		t.Errorf("changes = %+v, want target added to 00gg0xVALADWBPXOFZAK", plan.Changes)
+	}
+	if len(plan.Skipped) != 2 {
+		t.Errorf("skipped = %+v, want rule-managed and app groups", plan.Skipped)
+	}
+	buf := &bytes.Buffer{}
+	writeMirrorPlan(buf, plan)
+	for _, want := range []string{
+		"skipping group Fake Group 02 (00gg0xVALADWBPXOFZAS): membership is managed by a group rule",
+		"skipping group AD Group (00gappgroup000000000): APP_GROUP group membership is managed by Okta",
+		"  + target@example.com (00utarget)",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("plan missing %q:\n%s", want, buf.String())
+		}
+	}
+	if err := applyMembership(client, plan.Changes, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(client.added, ",") != "00gg0xVALADWBPXOFZAK/00utarget" {
+		t.Errorf("added = %v", client.added)
+	}
+}
+
+func TestUserMirrorYesIsOwnFlag(t *testing.T) {
+	t.Cleanup(func() { userMirrorCmd.Flags().Set("yes", "false") })
+	if err := userMirrorCmd.Flags().Set("yes", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if autoApprove {
+		t.Error("user mirror --yes also approved oktactl apply")
+	}
+}
+
+func TestUserMirrorFromSnapshot(t *testing.T) {
+	assertRequiresLiveOrg(t, "user", "mirror", "source@example.com", "target@example.com")
+}
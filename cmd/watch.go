@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/flynshue/oktactl/pkg/notify"
+	"github.com/flynshue/oktactl/pkg/watch"
+	"github.com/spf13/viper"
+)
+
+// groupMembers returns a function that fetches the users in a group.
+func groupMembers(os OktaService, groupID string) func() (watch.Members, error) {
+	return func() (watch.Members, error) {
+		users, err := os.ListOktaGroupUsers(groupID)
+		if err != nil {
+			return nil, err
+		}
+		members := watch.Members{}
+		for _, user := range users {
+			members[user.ID] = userLabel(user)
+		}
+		return members, nil
+	}
+}
+
+// appMembers returns a function that fetches the groups assigned to an app.
+func appMembers(os OktaService, appID string) func() (watch.Members, error) {
+	return func() (watch.Members, error) {
+		_, assignments, err := os.ListAppsGroups(appID)
+		if err != nil {
+			return nil, err
+		}
+		members := watch.Members{}
+		for _, assignment := range assignments {
+			members[assignment.GroupID] = assignment.Name
+		}
+		return members, nil
+	}
+}
+
+// eventWriter returns a function that writes each event to w as a table row or a line of NDJSON.
+func eventWriter(w io.Writer, format string) (func(watch.Event) error, error) {
+	switch format {
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		return func(e watch.Event) error { return enc.Encode(e) }, nil
+	case "table":
+		row := "%-20s  %-8s  %-22s  %s\n"
+		fmt.Fprintf(w, row, "Time", "Action", "Member ID", "Member")
+		return func(e watch.Event) error {
+			_, err := fmt.Fprintf(w, row, e.Time.Format(time.RFC3339), e.Action, e.MemberID, e.MemberName)
+			return err
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported output format %q, must be one of table, ndjson", format)
+	}
+}
+
+// newNotifier builds the notifier configured under the notify key of the config file.
+func newNotifier() (*notify.Notifier, error) {
+	var c notify.Config
+	if err := viper.UnmarshalKey("notify", &c); err != nil {
+		return nil, fmt.Errorf("reading notify config: %w", err)
+	}
+	return notify.New(c, nil)
+}
+
+// notifyingWriter writes each event with write and then sends it to the notifier until ctx
+// is done. Failed notifications are logged rather than stopping the caller.
+func notifyingWriter(ctx context.Context, write func(watch.Event) error, n *notify.Notifier, logger *log.Logger) func(watch.Event) error {
+	return func(e watch.Event) error {
+		if err := write(e); err != nil {
+			return err
+		}
+		if err := n.Notify(ctx, e); err != nil {
+			logger.Printf("notify: %s", err)
+		}
+		return nil
+	}
+}
+
+// runWatch prints membership changes of a group or app until interrupted, and sends them to
+// any notification sinks routed to it. Events are written to w; progress and errors go to stderr.
+func runWatch(w io.Writer, resource, id string, fetch func() (watch.Members, error), n *notify.Notifier, interval time.Duration, format string) error {
+	if interval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+	emit, err := eventWriter(w, format)
+	if err != nil {
+		return err
+	}
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if n.Routed(resource, id) {
+		// Notifications in flight are abandoned when the watch is interrupted.
+		emit = notifyingWriter(ctx, emit, n, logger)
+	}
+	watcher := &watch.Watcher{Resource: resource, ID: id, Interval: interval, Fetch: fetch, OnError: func(err error) { logger.Print(err) }}
+	started := func(m watch.Members) {
+		logger.Printf("watching %s %s (%d members), polling every %s", resource, id, len(m), interval)
+	}
+	return watcher.Run(ctx, started, emit)
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flynshue/oktactl/pkg/watch"
+	"github.com/spf13/viper"
+)
+
+func TestWatchMembers(t *testing.T) {
+	users, err := groupMembers(&MockOktaClient{}, "00g1emaKYZTWRYYRRTSK")()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 3 {
+		t.Errorf("group members = %v", users)
+	}
+	groups, err := appMembers(&MockOktaClient{}, "0oa1gjh63g214q0Hq0g4")()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) == 0 {
+		t.Error("expected assigned groups")
+	}
+}
+
+func TestEventWriter(t *testing.T) {
+	e := watch.Event{Time: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), Resource: "group", ResourceID: "00g1", Action: watch.Added, MemberID: "00u1", MemberName: "alex@example.com"}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"ndjson", `{"time":"2024-03-01T12:00:00Z","resource":"group","resourceId":"00g1","action":"added","memberId":"00u1","memberName":"alex@example.com"}` + "\n"},
+		{"table", "Time                  Action    Member ID               Member\n2024-03-01T12:00:00Z  added     00u1                    alex@example.com\n"},
+	}
+	for _, tt := range tests {
+		buf := &bytes.Buffer{}
+		emit, err := eventWriter(buf, tt.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := emit(e); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%s output = %q, want %q", tt.format, buf.String(), tt.want)
+		}
+	}
+	if _, err := eventWriter(&bytes.Buffer{}, "yaml"); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
+
+func TestNotifyingWriter(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+	}))
+	defer srv.Close()
+	viper.Set("notify", map[string]interface{}{
+		"sinks":  []map[string]interface{}{{"name": "security", "format": "slack", "url": srv.URL}},
+		"routes": []map[string]interface{}{{"group": "00g1emaKYZTWRYYRRTSK", "sinks": []string{"security"}}},
+	})
+	defer viper.Set("notify", nil)
+	n, err := newNotifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := 0
+	logs := &bytes.Buffer{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	emit := notifyingWriter(ctx, func(watch.Event) error { written++; return nil }, n, log.New(logs, "", 0))
+	e := watch.Event{Resource: watch.Group, ResourceID: "00g1emaKYZTWRYYRRTSK", Action: watch.Removed, MemberID: "00u1", MemberName: "alex@example.com"}
+	if err := emit(e); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"text": "oktactl: alex@example.com was removed from group 00g1emaKYZTWRYYRRTSK"}`; written != 1 || len(bodies) != 1 || bodies[0] != want {
+		t.Errorf("written = %d, bodies = %v", written, bodies)
+	}
+
+	srv.Close()
+	if err := emit(e); err != nil {
+		t.Errorf("notification failure stopped the watch: %v", err)
+	}
+	if !strings.Contains(logs.String(), "notify: sink security") {
+		t.Errorf("notification failure was not logged: %q", logs.String())
+	}
+
+	// Once the watch is interrupted, notifications are abandoned instead of retried.
+	cancel()
+	logs.Reset()
+	if err := emit(e); err != nil {
+		t.Errorf("cancelled notification stopped the watch: %v", err)
+	}
+	if !strings.Contains(logs.String(), "context canceled") {
+		t.Errorf("cancelled notification was not logged: %q", logs.String())
+	}
+}
+
+func TestE2EWatchSeesChanges(t *testing.T) {
+	admin, srv := newFakeOrg(t)
+	// The client is built from the config the way the watch commands build it, so it has the
+	// SDK's defaults other than the options they set.
+	t.Setenv("OKTA_TESTING_DISABLE_HTTPS_CHECK", "true")
+	viper.Set("org", srv.URL)
+	viper.Set("token", srv.Token)
+	t.Cleanup(func() { client = nil; viper.Set("org", nil); viper.Set("token", nil) })
+	client = nil
+	poll := groupMembers(newUncachedClient(), "00g1contract00000003")
+	if members, err := poll(); err != nil || len(members) != 1 {
+		t.Fatalf("first poll = %v, %v", members, err)
+	}
+	if err := admin.AddOktaGroupUser("00g1contract00000003", "00u1bobb000000000002"); err != nil {
+		t.Fatal(err)
+	}
+	if members, err := poll(); err != nil || len(members) != 2 {
+		t.Errorf("poll after a member was added elsewhere = %v, %v, want 2 members", members, err)
+	}
+}
+
+func TestWatchFromSnapshot(t *testing.T) {
+	assertRequiresLiveOrg(t, "watch", "group", "00g1contract00000003")
+	assertRequiresLiveOrg(t, "watch", "app", "0oa1aws0000000000001")
+}
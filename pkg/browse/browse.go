@@ -0,0 +1,205 @@
+// Package browse navigates an org's apps, groups and users for `oktactl ui`. A search lists
+// apps or groups, and opening a row drills down into what it links to: an app's assigned
+// groups, a group's members, or a user's groups.
+//
+// Fetching a view only reads from the Source, so it can run off the UI goroutine; the Browser's
+// history must only be changed from one goroutine.
+package browse
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/flynshue/oktactl/pkg/okta-api/v2/oktaapi"
+)
+
+// Source is the org data the browser reads. OktaClient and Snapshot implement it.
+type Source interface {
+	ListApps(name string) ([]oktaapi.App, error)
+	ListAppsGroups(appID string) (oktaapi.App, []oktaapi.GroupAssignmentResp, error)
+	ListOktaGroups(name string) ([]oktaapi.Group, error)
+	ListOktaGroupUsers(groupID string) ([]oktaapi.User, error)
+	ListOktaUserGroups(userID string) ([]oktaapi.Group, error)
+}
+
+// Kind is the kind of resource a row shows.
+type Kind string
+
+const (
+	App   Kind = "app"
+	Group Kind = "group"
+	User  Kind = "user"
+)
+
+// Row is one resource in a view. Name is used to title the view the row opens.
+type Row struct {
+	Kind  Kind
+	ID    string
+	Name  string
+	Cells []string
+}
+
+// View is a table of resources.
+type View struct {
+	Title   string
+	Columns []string
+	Rows    []Row
+}
+
+// Browser fetches views from a Source and keeps the history of views opened since the last search.
+type Browser struct {
+	src     Source
+	history []*View
+}
+
+func New(src Source) *Browser {
+	return &Browser{src: src}
+}
+
+// Search returns the apps or groups whose name starts with query.
+func (b *Browser) Search(kind Kind, query string) (*View, error) {
+	switch kind {
+	case App:
+		apps, err := b.src.ListApps(query)
+		if err != nil {
+			return nil, err
+		}
+		return appsView(fmt.Sprintf("Apps matching %q", query), apps), nil
+	case Group:
+		groups, err := b.src.ListOktaGroups(query)
+		if err != nil {
+			return nil, err
+		}
+		return groupsView(fmt.Sprintf("Groups matching %q", query), groups), nil
+	}
+	return nil, fmt.Errorf("cannot search %ss", kind)
+}
+
+// Open returns the view row drills down into.
+func (b *Browser) Open(row Row) (*View, error) {
+	switch row.Kind {
+	case App:
+		app, assignments, err := b.src.ListAppsGroups(row.ID)
+		if err != nil {
+			return nil, err
+		}
+		name := app.Label
+		if name == "" {
+			name = row.Name
+		}
+		v := &View{Title: "Groups assigned to " + name, Columns: []string{"ID", "Name", "Priority", "Roles"}, Rows: []Row{}}
+		for _, a := range assignments {
+			roles := append([]string{}, a.SAMLRoles...)
+			if a.Role != "" {
+				roles = append(roles, a.Role)
+			}
+			v.Rows = append(v.Rows, Row{Kind: Group, ID: a.GroupID, Name: a.Name, Cells: []string{a.GroupID, a.Name, strconv.Itoa(a.Priority), strings.Join(roles, ", ")}})
+		}
+		return v, nil
+	case Group:
+		users, err := b.src.ListOktaGroupUsers(row.ID)
+		if err != nil {
+			return nil, err
+		}
+		v := &View{Title: "Members of " + row.Name, Columns: []string{"ID", "Login", "Name", "Email", "Status"}, Rows: []Row{}}
+		for _, u := range users {
+			name := strings.TrimSpace(u.FirstName + " " + u.LastName)
+			label := u.Login
+			if label == "" {
+				label = u.Email
+			}
+			v.Rows = append(v.Rows, Row{Kind: User, ID: u.ID, Name: label, Cells: []string{u.ID, u.Login, name, u.Email, u.Status}})
+		}
+		return v, nil
+	case User:
+		groups, err := b.src.ListOktaUserGroups(row.ID)
+		if err != nil {
+			return nil, err
+		}
+		return groupsView("Groups of "+row.Name, groups), nil
+	}
+	return nil, fmt.Errorf("cannot open %s %s", row.Kind, row.ID)
+}
+
+func appsView(title string, apps []oktaapi.App) *View {
+	v := &View{Title: title, Columns: []string{"ID", "Label", "Name"}, Rows: []Row{}}
+	for _, app := range apps {
+		v.Rows = append(v.Rows, Row{Kind: App, ID: app.ID, Name: app.Label, Cells: []string{app.ID, app.Label, app.Name}})
+	}
+	return v
+}
+
+func groupsView(title string, groups []oktaapi.Group) *View {
+	v := &View{Title: title, Columns: []string{"ID", "Name", "Type"}, Rows: []Row{}}
+	for _, g := range groups {
+		v.Rows = append(v.Rows, Row{Kind: Group, ID: g.ID, Name: g.Name, Cells: []string{g.ID, g.Name, g.Type}})
+	}
+	return v
+}
+
+// Reset starts a new history with v, the result of a search.
+func (b *Browser) Reset(v *View) {
+	b.history = []*View{v}
+}
+
+// Push adds v, the result of opening a row of the current view, to the history.
+func (b *Browser) Push(v *View) {
+	b.history = append(b.history, v)
+}
+
+// Back returns to the previous view. It reports false when there is none.
+func (b *Browser) Back() bool {
+	if len(b.history) < 2 {
+		return false
+	}
+	b.history = b.history[:len(b.history)-1]
+	return true
+}
+
+// Current returns the view being shown, or nil before the first search.
+func (b *Browser) Current() *View {
+	if len(b.history) == 0 {
+		return nil
+	}
+	return b.history[len(b.history)-1]
+}
+
+// Breadcrumb returns the titles of the views in the history.
+func (b *Browser) Breadcrumb() string {
+	titles := make([]string, 0, len(b.history))
+	for _, v := range b.history {
+		titles = append(titles, v.Title)
+	}
+	return strings.Join(titles, " > ")
+}
+
+// Export writes the view as csv, or as json with one object per row keyed by column.
+func (v *View) Export(w io.Writer, format string) error {
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(v.Columns)
+		for _, row := range v.Rows {
+			cw.Write(row.Cells)
+		}
+		cw.Flush()
+		return cw.Error()
+	case "json":
+		records := make([]map[string]string, 0, len(v.Rows))
+		for _, row := range v.Rows {
+			record := map[string]string{}
+			for i, col := range v.Columns {
+				record[col] = row.Cells[i]
+			}
+			records = append(records, record)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	}
+	return fmt.Errorf("unsupported export format %q, must be csv or json", format)
+}
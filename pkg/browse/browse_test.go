@@ -0,0 +1,129 @@
+package browse
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/flynshue/oktactl/pkg/fakeokta"
+	"github.com/flynshue/oktactl/pkg/okta-api/v2/oktaapi"
+)
+
+func newFakeBrowser(t *testing.T) *Browser {
+	t.Helper()
+	seed, err := fakeokta.LoadSeed("../fakeokta/testdata/seed.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := fakeokta.New(seed)
+	t.Cleanup(srv.Close)
+	client, err := oktaapi.NewClient(srv.URL, srv.Token, srv.Options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(client)
+}
+
+func ids(v *View) string {
+	ids := []string{}
+	for _, row := range v.Rows {
+		ids = append(ids, row.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestDrillDown(t *testing.T) {
+	b := newFakeBrowser(t)
+	apps, err := b.Search(App, "aws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(apps); got != "0oa1aws0000000000001" {
+		t.Fatalf("Search(app, aws) = %s", got)
+	}
+	b.Reset(apps)
+
+	groups, err := b.Open(apps.Rows[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if groups.Title != "Groups assigned to AWS Prod" || ids(groups) != "00g1admins0000000001,00g1eng0000000000002" {
+		t.Fatalf("Open(AWS Prod) = %q %s", groups.Title, ids(groups))
+	}
+	if got := strings.Join(groups.Rows[0].Cells, "|"); got != "00g1admins0000000001|Super Admins|0|Admin, admin" {
+		t.Errorf("first assignment = %s", got)
+	}
+	b.Push(groups)
+
+	members, err := b.Open(groups.Rows[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if members.Title != "Members of Engineering" || ids(members) != "00u1alex000000000001,00u1bobb000000000002" {
+		t.Fatalf("Open(Engineering) = %q %s", members.Title, ids(members))
+	}
+	b.Push(members)
+
+	userGroups, err := b.Open(members.Rows[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userGroups.Title != "Groups of bob@example.com" || ids(userGroups) != "00g0everyone00000000,00g1eng0000000000002" {
+		t.Fatalf("Open(bob) = %q %s", userGroups.Title, ids(userGroups))
+	}
+	b.Push(userGroups)
+
+	if got := b.Breadcrumb(); got != `Apps matching "aws" > Groups assigned to AWS Prod > Members of Engineering > Groups of bob@example.com` {
+		t.Errorf("Breadcrumb = %s", got)
+	}
+	for _, want := range []*View{members, groups, apps} {
+		if !b.Back() || b.Current() != want {
+			t.Fatalf("Back did not return to %q", want.Title)
+		}
+	}
+	if b.Back() {
+		t.Error("Back past the search")
+	}
+}
+
+func TestSearchGroups(t *testing.T) {
+	b := newFakeBrowser(t)
+	v, err := b.Search(Group, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Rows) != 4 || v.Rows[0].Cells[2] != "BUILT_IN" {
+		t.Errorf("Search(group) = %+v", v.Rows)
+	}
+	if _, err := b.Search(User, "alex"); err == nil {
+		t.Error("expected error searching users")
+	}
+}
+
+func TestExport(t *testing.T) {
+	v := &View{
+		Title:   "Members of Engineering",
+		Columns: []string{"ID", "Login"},
+		Rows: []Row{
+			{Kind: User, ID: "00u1alex000000000001", Cells: []string{"00u1alex000000000001", "alex@example.com"}},
+			{Kind: User, ID: "00u1bobb000000000002", Cells: []string{"00u1bobb000000000002", "bob, jr@example.com"}},
+		},
+	}
+	buf := &bytes.Buffer{}
+	if err := v.Export(buf, "csv"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "ID,Login\n00u1alex000000000001,alex@example.com\n00u1bobb000000000002,\"bob, jr@example.com\"\n"; buf.String() != want {
+		t.Errorf("csv export:\n%s\nwant:\n%s", buf, want)
+	}
+	buf.Reset()
+	if err := v.Export(buf, "json"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"Login": "alex@example.com"`) {
+		t.Errorf("json export:\n%s", buf)
+	}
+	if err := v.Export(buf, "xml"); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
@@ -0,0 +1,166 @@
+// Package eventhook receives Okta event hooks.
+//
+// Okta verifies an event hook endpoint once with a GET request carrying the
+// X-Okta-Verification-Challenge header, then delivers batches of System Log
+// events as POST requests. Both carry the authorization header configured on
+// the hook in Okta.
+package eventhook
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/flynshue/oktactl/pkg/watch"
+)
+
+// VerificationHeader carries the one-time verification challenge.
+const VerificationHeader = "X-Okta-Verification-Challenge"
+
+// maxBody bounds the size of a delivery. Okta sends at most 50 events per request.
+const maxBody = 5 << 20
+
+// Event types that change group or app membership.
+const (
+	GroupUserAdd             = "group.user_membership.add"
+	GroupUserRemove          = "group.user_membership.remove"
+	AppGroupAssignmentAdd    = "group.application_assignment.add"
+	AppGroupAssignmentRemove = "group.application_assignment.remove"
+)
+
+// Payload is the body of an event hook delivery.
+type Payload struct {
+	EventType string    `json:"eventType"`
+	EventID   string    `json:"eventId"`
+	EventTime time.Time `json:"eventTime"`
+	Data      struct {
+		Events []Event `json:"events"`
+	} `json:"data"`
+}
+
+// Event is a System Log event delivered by an event hook.
+type Event struct {
+	UUID           string    `json:"uuid"`
+	Published      time.Time `json:"published"`
+	EventType      string    `json:"eventType"`
+	DisplayMessage string    `json:"displayMessage"`
+	Severity       string    `json:"severity"`
+	Outcome        struct {
+		Result string `json:"result"`
+		Reason string `json:"reason,omitempty"`
+	} `json:"outcome"`
+	Actor  Actor    `json:"actor"`
+	Target []Target `json:"target"`
+}
+
+// Actor is who performed an event.
+type Actor struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	AlternateID string `json:"alternateId"`
+	DisplayName string `json:"displayName"`
+}
+
+// Target is an entity an event acted on.
+type Target struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	AlternateID string `json:"alternateId"`
+	DisplayName string `json:"displayName"`
+}
+
+// target returns the first target of type t.
+func (e Event) target(t string) (Target, bool) {
+	for _, target := range e.Target {
+		if target.Type == t {
+			return target, true
+		}
+	}
+	return Target{}, false
+}
+
+// MembershipChange describes a group membership or app group assignment event as a watch.Event,
+// so it can be sent to the same notification sinks as the changes found by polling.
+func (e Event) MembershipChange() (watch.Event, bool) {
+	var resourceType, memberType, action, resource string
+	switch e.EventType {
+	case GroupUserAdd, GroupUserRemove:
+		resource, resourceType, memberType = watch.Group, "UserGroup", "User"
+	case AppGroupAssignmentAdd, AppGroupAssignmentRemove:
+		resource, resourceType, memberType = watch.App, "AppInstance", "UserGroup"
+	default:
+		return watch.Event{}, false
+	}
+	action = watch.Added
+	if e.EventType == GroupUserRemove || e.EventType == AppGroupAssignmentRemove {
+		action = watch.Removed
+	}
+	r, ok := e.target(resourceType)
+	if !ok {
+		return watch.Event{}, false
+	}
+	m, ok := e.target(memberType)
+	if !ok {
+		return watch.Event{}, false
+	}
+	name := m.AlternateID
+	if name == "" || name == "unknown" {
+		name = m.DisplayName
+	}
+	return watch.Event{Time: e.Published, Resource: resource, ResourceID: r.ID, Action: action, MemberID: m.ID, MemberName: name}, true
+}
+
+// Handler serves an Okta event hook endpoint.
+type Handler struct {
+	// Secret is the authorization header value configured on the event hook in Okta.
+	Secret string
+	// Handle is called for each delivered event before the delivery is acknowledged, so it
+	// should only record the event and leave slow work, such as sending notifications, to
+	// another goroutine: Okta resends deliveries that are not acknowledged within 3 seconds.
+	// Errors are logged; the delivery is still acknowledged so that Okta does not resend the
+	// events that were handled.
+	Handle func(context.Context, Event) error
+	Logger *log.Logger
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(h.Secret)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		challenge := r.Header.Get(VerificationHeader)
+		if challenge == "" {
+			http.Error(w, "missing "+VerificationHeader+" header", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"verification": challenge})
+		h.logf("verified event hook")
+	case http.MethodPost:
+		var p Payload
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBody)).Decode(&p); err != nil {
+			http.Error(w, "invalid event hook payload", http.StatusBadRequest)
+			return
+		}
+		for _, e := range p.Data.Events {
+			if err := h.Handle(r.Context(), e); err != nil {
+				h.logf("handling event %s (%s): %s", e.UUID, e.EventType, err)
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) logf(format string, v ...interface{}) {
+	if h.Logger != nil {
+		h.Logger.Printf(format, v...)
+	}
+}
@@ -0,0 +1,147 @@
+package eventhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flynshue/oktactl/pkg/watch"
+)
+
+const delivery = `{
+  "eventType": "com.okta.event_hook",
+  "eventTypeVersion": "1.0",
+  "cloudEventsVersion": "0.1",
+  "source": "https://example.okta.com/api/v1/eventHooks/whoql0HfiLGPWc8Fx0g4",
+  "eventId": "b5a188b9-5ece-4636-b041-482ffda96311",
+  "eventTime": "2024-03-01T12:00:00.000Z",
+  "contentType": "application/json",
+  "data": {
+    "events": [
+      {
+        "uuid": "f1a5f1f0-d7a5-11ee-9c2d-1b2a4d8e0c11",
+        "published": "2024-03-01T11:59:58.000Z",
+        "eventType": "group.user_membership.add",
+        "displayMessage": "Add user to group membership",
+        "severity": "INFO",
+        "outcome": {"result": "SUCCESS"},
+        "actor": {"id": "00uadmin", "type": "User", "alternateId": "admin@example.com", "displayName": "Admin"},
+        "target": [
+          {"id": "00u1", "type": "User", "alternateId": "alex@example.com", "displayName": "Alex"},
+          {"id": "00g1emaKYZTWRYYRRTSK", "type": "UserGroup", "alternateId": "unknown", "displayName": "Super Admins"}
+        ]
+      },
+      {
+        "uuid": "f1a5f1f0-d7a5-11ee-9c2d-1b2a4d8e0c12",
+        "published": "2024-03-01T11:59:59.000Z",
+        "eventType": "group.application_assignment.remove",
+        "displayMessage": "Remove assigned application from group",
+        "severity": "INFO",
+        "outcome": {"result": "SUCCESS"},
+        "actor": {"id": "00uadmin", "type": "User", "alternateId": "admin@example.com", "displayName": "Admin"},
+        "target": [
+          {"id": "0oa1gjh63g214q0Hq0g4", "type": "AppInstance", "alternateId": "Test App", "displayName": "Test App"},
+          {"id": "00g1emaKYZTWRYYRRTSK", "type": "UserGroup", "alternateId": "unknown", "displayName": "Super Admins"}
+        ]
+      },
+      {
+        "uuid": "f1a5f1f0-d7a5-11ee-9c2d-1b2a4d8e0c13",
+        "published": "2024-03-01T11:59:59.000Z",
+        "eventType": "user.session.start",
+        "displayMessage": "User login to Okta",
+        "severity": "INFO",
+        "outcome": {"result": "SUCCESS"},
+        "actor": {"id": "00u1", "type": "User", "alternateId": "alex@example.com", "displayName": "Alex"},
+        "target": []
+      }
+    ]
+  }
+}`
+
+func newHandler(events *[]Event) *Handler {
+	return &Handler{
+		Secret: "s3cret",
+		Handle: func(_ context.Context, e Event) error {
+			*events = append(*events, e)
+			if e.EventType == "user.session.start" {
+				return errors.New("sink unavailable")
+			}
+			return nil
+		},
+	}
+}
+
+func TestVerification(t *testing.T) {
+	h := newHandler(&[]Event{})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "s3cret")
+	req.Header.Set(VerificationHeader, "X5SlvxZBwZ9Nu5pnQmJCq2R8ynEVBTaEdUwlbi-F")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	var got map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || got["verification"] != "X5SlvxZBwZ9Nu5pnQmJCq2R8ynEVBTaEdUwlbi-F" {
+		t.Errorf("verification response = %s", rec.Body.String())
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	var events []Event
+	h := newHandler(&events)
+	for _, auth := range []string{"", "wrong", "s3cret "} {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(delivery))
+		req.Header.Set("Authorization", auth)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: status = %d, want 401", auth, rec.Code)
+		}
+	}
+	if len(events) != 0 {
+		t.Errorf("unauthorized delivery was handled: %+v", events)
+	}
+}
+
+func TestDelivery(t *testing.T) {
+	var events []Event
+	h := newHandler(&events)
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(delivery))
+	req.Header.Set("Authorization", "s3cret")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if len(events) != 3 {
+		t.Fatalf("handled %d events, want 3", len(events))
+	}
+	if events[0].Actor.AlternateID != "admin@example.com" || events[0].Outcome.Result != "SUCCESS" {
+		t.Errorf("event = %+v", events[0])
+	}
+
+	add, ok := events[0].MembershipChange()
+	if !ok || add.Resource != watch.Group || add.ResourceID != "00g1emaKYZTWRYYRRTSK" || add.Action != watch.Added || add.MemberID != "00u1" || add.MemberName != "alex@example.com" {
+		t.Errorf("group membership change = %+v, %v", add, ok)
+	}
+	rm, ok := events[1].MembershipChange()
+	if !ok || rm.Resource != watch.App || rm.ResourceID != "0oa1gjh63g214q0Hq0g4" || rm.Action != watch.Removed || rm.MemberID != "00g1emaKYZTWRYYRRTSK" || rm.MemberName != "Super Admins" {
+		t.Errorf("app assignment change = %+v, %v", rm, ok)
+	}
+	if _, ok := events[2].MembershipChange(); ok {
+		t.Error("session start reported as a membership change")
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("not json"))
+	req.Header.Set("Authorization", "s3cret")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid payload status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,174 @@
+// Package expiry stores the time-bound group memberships granted by oktactl
+// so they can be removed once they expire.
+package expiry
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Membership is a user's group membership that must be removed at Expires.
+type Membership struct {
+	GroupID string    `json:"groupId"`
+	UserID  string    `json:"userId"`
+	User    string    `json:"user,omitempty"`
+	Added   time.Time `json:"added"`
+	Expires time.Time `json:"expires"`
+}
+
+// Store is a JSON file of time-bound memberships. Several oktactl runs may change the file at
+// once, e.g. `group add-user` while `reconcile expirations --loop` runs, so a Store keeps the
+// changes made since Load and Save applies them to the file as it is then, under a lock.
+type Store struct {
+	path        string
+	Memberships []Membership
+	changes     []change
+}
+
+// change is a Put of m, a Remove of m's group and user, or an Expire of m, which only
+// removes the entry if its Expires is still m's.
+type change struct {
+	put    bool
+	expire bool
+	m      Membership
+}
+
+// DefaultPath is the store location used when none is configured.
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".oktactl", "expirations.json"), nil
+}
+
+// Load reads the store at path. A missing file is an empty store.
+func Load(path string) (*Store, error) {
+	memberships, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{path: path, Memberships: memberships}, nil
+}
+
+func read(path string) ([]Membership, error) {
+	memberships := []Membership{}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return memberships, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &memberships); err != nil {
+		return nil, err
+	}
+	return memberships, nil
+}
+
+// Save applies the changes made since Load or the last Save to the file, replacing it
+// atomically. The file is re-read under an exclusive lock first, so that changes saved by
+// other runs in the meantime are kept.
+func (s *Store) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	unlock, err := lock(s.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	memberships, err := read(s.path)
+	if err != nil {
+		return err
+	}
+	for _, c := range s.changes {
+		switch {
+		case c.expire:
+			memberships = removeExpired(memberships, c.m)
+		case c.put:
+			memberships = append(remove(memberships, c.m.GroupID, c.m.UserID), c.m)
+		default:
+			memberships = remove(memberships, c.m.GroupID, c.m.UserID)
+		}
+	}
+	sort.Slice(memberships, func(i, j int) bool { return memberships[i].Expires.Before(memberships[j].Expires) })
+	b, err := json.MarshalIndent(memberships, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	s.Memberships, s.changes = memberships, nil
+	return nil
+}
+
+// Get returns the membership of userID in groupID, if it is time-bound.
+func (s *Store) Get(groupID, userID string) (Membership, bool) {
+	for _, m := range s.Memberships {
+		if m.GroupID == groupID && m.UserID == userID {
+			return m, true
+		}
+	}
+	return Membership{}, false
+}
+
+// Put adds m, replacing any existing expiry for the same group and user.
+func (s *Store) Put(m Membership) {
+	s.Memberships = append(remove(s.Memberships, m.GroupID, m.UserID), m)
+	s.changes = append(s.changes, change{put: true, m: m})
+}
+
+// Remove forgets the expiry of userID's membership in groupID.
+func (s *Store) Remove(groupID, userID string) {
+	s.Memberships = remove(s.Memberships, groupID, userID)
+	s.changes = append(s.changes, change{m: Membership{GroupID: groupID, UserID: userID}})
+}
+
+// Expire forgets m after it has been removed from its group. Unlike Remove, it keeps the
+// expiry if another run has since extended it, so the later expiry is still reconciled.
+func (s *Store) Expire(m Membership) {
+	s.Memberships = removeExpired(s.Memberships, m)
+	s.changes = append(s.changes, change{expire: true, m: m})
+}
+
+func removeExpired(memberships []Membership, expired Membership) []Membership {
+	kept := memberships[:0]
+	for _, m := range memberships {
+		if m.GroupID != expired.GroupID || m.UserID != expired.UserID || !m.Expires.Equal(expired.Expires) {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+func remove(memberships []Membership, groupID, userID string) []Membership {
+	kept := memberships[:0]
+	for _, m := range memberships {
+		if m.GroupID != groupID || m.UserID != userID {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// Due returns the memberships that have expired at now.
+func (s *Store) Due(now time.Time) []Membership {
+	due := []Membership{}
+	for _, m := range s.Memberships {
+		if !m.Expires.After(now) {
+			due = append(due, m)
+		}
+	}
+	return due
+}
@@ -0,0 +1,143 @@
+package expiry
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "expirations.json")
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.Put(Membership{GroupID: "00g1", UserID: "00u1", Added: now, Expires: now.Add(time.Hour)})
+	s.Put(Membership{GroupID: "00g1", UserID: "00u2", Added: now, Expires: now.Add(-time.Minute)})
+	s.Put(Membership{GroupID: "00g1", UserID: "00u1", Added: now, Expires: now.Add(72 * time.Hour)})
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Memberships) != 2 {
+		t.Fatalf("got %d memberships, want 2", len(loaded.Memberships))
+	}
+	if m, ok := loaded.Get("00g1", "00u1"); !ok || !m.Expires.Equal(now.Add(72*time.Hour)) {
+		t.Errorf("Get(00g1, 00u1) = %+v, %v, want extended expiry", m, ok)
+	}
+	due := loaded.Due(now)
+	if len(due) != 1 || due[0].UserID != "00u2" {
+		t.Errorf("Due = %+v, want 00u2", due)
+	}
+	loaded.Remove("00g1", "00u2")
+	if len(loaded.Due(now.Add(100*time.Hour))) != 1 {
+		t.Errorf("Remove did not forget 00u2: %+v", loaded.Memberships)
+	}
+}
+
+func TestStoreConcurrentSaves(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "expirations.json")
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	seed, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed.Put(Membership{GroupID: "00g1", UserID: "00u1", Expires: now})
+	if err := seed.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A reconcile run and an add-user run load the store at the same time.
+	reconcile, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	add, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	add.Put(Membership{GroupID: "00g1", UserID: "00u2", Expires: now.Add(time.Hour)})
+	reconcile.Remove("00g1", "00u1")
+	if err := add.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := reconcile.Save(); err != nil {
+		t.Fatal(err)
+	}
+	add.Put(Membership{GroupID: "00g2", UserID: "00u3", Expires: now.Add(2 * time.Hour)})
+	if err := add.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Memberships) != 2 {
+		t.Fatalf("memberships = %+v, want 00u2 and 00u3", loaded.Memberships)
+	}
+	if _, ok := loaded.Get("00g1", "00u1"); ok {
+		t.Error("the reconciled membership came back")
+	}
+	if _, ok := loaded.Get("00g1", "00u2"); !ok {
+		t.Error("the membership added while reconciling was lost")
+	}
+	if len(reconcile.Memberships) != 1 || reconcile.Memberships[0].UserID != "00u2" {
+		t.Errorf("store after saving = %+v, want the memberships saved by both", reconcile.Memberships)
+	}
+}
+
+func TestStoreExtendWhileReconciling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "expirations.json")
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	seed, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed.Put(Membership{GroupID: "00g1", UserID: "00u1", Expires: now})
+	if err := seed.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reconcile acts on the expired entry while add-user extends the same membership.
+	reconcile, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	add, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	add.Put(Membership{GroupID: "00g1", UserID: "00u1", Expires: now.Add(24 * time.Hour)})
+	if err := add.Save(); err != nil {
+		t.Fatal(err)
+	}
+	due := reconcile.Due(now)
+	if len(due) != 1 {
+		t.Fatalf("Due = %+v, want the seeded membership", due)
+	}
+	reconcile.Expire(due[0])
+	if err := reconcile.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := loaded.Get("00g1", "00u1"); !ok || !m.Expires.Equal(now.Add(24*time.Hour)) {
+		t.Errorf("Get(00g1, 00u1) = %+v, %v, want the extension kept", m, ok)
+	}
+
+	loaded.Expire(Membership{GroupID: "00g1", UserID: "00u1", Expires: now.Add(24 * time.Hour)})
+	if err := loaded.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Memberships) != 0 {
+		t.Errorf("memberships = %+v, want none after expiring the extension", loaded.Memberships)
+	}
+}
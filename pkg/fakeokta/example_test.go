@@ -0,0 +1,38 @@
+package fakeokta_test
+
+import (
+	"fmt"
+
+	"github.com/flynshue/oktactl/pkg/fakeokta"
+	"github.com/flynshue/oktactl/pkg/okta-api/v2/oktaapi"
+)
+
+func ExampleNew() {
+	seed, err := fakeokta.ParseSeed([]byte(`
+users:
+  - id: 00u1alex000000000001
+    profile: {login: alex@example.com, email: alex@example.com}
+groups:
+  - id: 00g1admins0000000001
+    profile: {name: Super Admins}
+`))
+	if err != nil {
+		panic(err)
+	}
+	srv := fakeokta.New(seed)
+	defer srv.Close()
+
+	client, err := oktaapi.NewClient(srv.URL, srv.Token, srv.Options()...)
+	if err != nil {
+		panic(err)
+	}
+	if err := client.AddOktaGroupUser("00g1admins0000000001", "00u1alex000000000001"); err != nil {
+		panic(err)
+	}
+	users, _ := client.ListOktaGroupUsers("00g1admins0000000001")
+	fmt.Println(users[0].Login)
+	fmt.Println(srv.Logs()[0].EventType)
+	// Output:
+	// alex@example.com
+	// group.user_membership.add
+}
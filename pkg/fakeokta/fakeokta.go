@@ -0,0 +1,762 @@
+// Package fakeokta is an in-memory stand-in for the Okta management API, for tests and demos.
+//
+// It serves the apps, groups, group rules, users and System Log endpoints that oktactl
+// uses from an httptest server seeded from YAML. Writes change the in-memory state and
+// are recorded as System Log events. Lists are paginated with Link headers and every
+// response carries rate limit headers.
+package fakeokta
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+)
+
+// DefaultToken is the API token the server accepts unless Token is changed.
+const DefaultToken = "fake-okta-token"
+
+// Server is a fake Okta org.
+type Server struct {
+	*httptest.Server
+
+	// Token is the API token requests must carry as "SSWS <token>".
+	Token string
+	// MaxPageSize caps the limit of every list request so tests can force pagination. 0 is no cap.
+	MaxPageSize int
+	// RateLimit is the number of requests allowed per RateLimitWindow. 0 is unlimited.
+	RateLimit       int
+	RateLimitWindow time.Duration
+	// Now is the server's clock. It defaults to time.Now.
+	Now func() time.Time
+
+	mu          sync.Mutex
+	users       []*User
+	groups      []*Group
+	apps        []*App
+	rules       []*GroupRule
+	logs        []LogEvent
+	sessions    map[string]int
+	updated     map[string]time.Time
+	windowStart time.Time
+	requests    int
+	nextID      int
+}
+
+// New starts a server with the state in seed. Close it when done.
+func New(seed *Seed) *Server {
+	s := &Server{Token: DefaultToken, RateLimitWindow: time.Minute, sessions: map[string]int{}, updated: map[string]time.Time{}}
+	if seed == nil {
+		seed = &Seed{}
+	}
+	for i := range seed.Users {
+		u := seed.Users[i]
+		if u.ID == "" {
+			u.ID = s.newID("00u")
+		}
+		if u.Status == "" {
+			u.Status = "ACTIVE"
+		}
+		s.users = append(s.users, &u)
+	}
+	for i := range seed.Groups {
+		g := seed.Groups[i]
+		if g.ID == "" {
+			g.ID = s.newID("00g")
+		}
+		if g.Type == "" {
+			g.Type = "OKTA_GROUP"
+		}
+		members := []string{}
+		for _, ref := range g.Members {
+			if u := s.user(ref); u != nil {
+				members = append(members, u.ID)
+			} else {
+				members = append(members, ref)
+			}
+		}
+		g.Members = members
+		s.groups = append(s.groups, &g)
+	}
+	for i := range seed.Apps {
+		a := seed.Apps[i]
+		if a.ID == "" {
+			a.ID = s.newID("0oa")
+		}
+		if a.Status == "" {
+			a.Status = "ACTIVE"
+		}
+		s.apps = append(s.apps, &a)
+	}
+	for i := range seed.GroupRules {
+		r := seed.GroupRules[i]
+		if r.ID == "" {
+			r.ID = s.newID("0pr")
+		}
+		if r.Status == "" {
+			r.Status = "ACTIVE"
+		}
+		s.rules = append(s.rules, &r)
+	}
+	s.logs = append(s.logs, seed.Logs...)
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// Options returns the SDK settings needed to talk to the server, which serves plain HTTP.
+// Everything else is left at the SDK's defaults, as in production, including its response
+// cache; tests that read what another client changed turn it off with okta.WithCache(false).
+func (s *Server) Options() []okta.ConfigSetter {
+	return []okta.ConfigSetter{
+		okta.WithTestingDisableHttpsCheck(true),
+		okta.WithHttpClientPtr(s.Client()),
+	}
+}
+
+// Members returns the IDs of the users in a group.
+func (s *Server) Members(groupID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if g := s.group(groupID); g != nil {
+		return append([]string{}, g.Members...)
+	}
+	return nil
+}
+
+// User returns the user with the given ID or login.
+func (s *Server) User(ref string) (User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u := s.user(ref); u != nil {
+		return *u, true
+	}
+	return User{}, false
+}
+
+// Sessions returns the number of times the user's sessions were cleared.
+func (s *Server) Sessions(userID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessions[userID]
+}
+
+// Logs returns every System Log event, oldest first.
+func (s *Server) Logs() []LogEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]LogEvent{}, s.logs...)
+}
+
+func (s *Server) now() time.Time {
+	if s.Now != nil {
+		return s.Now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+func (s *Server) newID(prefix string) string {
+	s.nextID++
+	return fmt.Sprintf("%sfake%012d", prefix, s.nextID)
+}
+
+func (s *Server) user(ref string) *User {
+	for _, u := range s.users {
+		if u.ID == ref || strings.EqualFold(u.Profile.Login, ref) {
+			return u
+		}
+	}
+	return nil
+}
+
+func (s *Server) group(id string) *Group {
+	for _, g := range s.groups {
+		if g.ID == id {
+			return g
+		}
+	}
+	return nil
+}
+
+func (s *Server) app(id string) *App {
+	for _, a := range s.apps {
+		if a.ID == id {
+			return a
+		}
+	}
+	return nil
+}
+
+// apiError is the body of an Okta error response.
+type apiError struct {
+	ErrorCode    string        `json:"errorCode"`
+	ErrorSummary string        `json:"errorSummary"`
+	ErrorLink    string        `json:"errorLink"`
+	ErrorID      string        `json:"errorId"`
+	ErrorCauses  []interface{} `json:"errorCauses"`
+}
+
+func (s *Server) writeError(w http.ResponseWriter, status int, code, summary string) {
+	s.writeJSON(w, status, apiError{ErrorCode: code, ErrorSummary: summary, ErrorLink: code, ErrorID: s.newID("oae"), ErrorCauses: []interface{}{}})
+}
+
+func (s *Server) notFound(w http.ResponseWriter, id, kind string) {
+	s.writeError(w, http.StatusNotFound, "E0000007", fmt.Sprintf("Not found: Resource not found: %s (%s)", id, kind))
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// rateLimit sets the rate limit headers and reports whether the request is within the limit.
+func (s *Server) rateLimit(w http.ResponseWriter) bool {
+	now := s.now()
+	if s.windowStart.IsZero() || !now.Before(s.windowStart.Add(s.RateLimitWindow)) {
+		s.windowStart, s.requests = now, 0
+	}
+	s.requests++
+	limit := s.RateLimit
+	if limit == 0 {
+		limit = 600
+	}
+	remaining := limit - s.requests
+	if remaining < 0 {
+		remaining = 0
+	}
+	h := w.Header()
+	h.Set("X-Rate-Limit-Limit", strconv.Itoa(limit))
+	h.Set("X-Rate-Limit-Remaining", strconv.Itoa(remaining))
+	h.Set("X-Rate-Limit-Reset", strconv.FormatInt(s.windowStart.Add(s.RateLimitWindow).Unix(), 10))
+	return s.RateLimit == 0 || s.requests <= s.RateLimit
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("X-Okta-Request-Id", s.newID("req"))
+	if r.Header.Get("Authorization") != "SSWS "+s.Token {
+		s.writeError(w, http.StatusUnauthorized, "E0000011", "Invalid token provided")
+		return
+	}
+	if !s.rateLimit(w) {
+		s.writeError(w, http.StatusTooManyRequests, "E0000047", "API call exceeded rate limit due to too many requests.")
+		return
+	}
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1"), "/")
+	parts := strings.Split(path, "/")
+	route := r.Method + " " + parts[0]
+	switch {
+	case route == "GET apps" && len(parts) == 1:
+		s.listApps(w, r)
+	case route == "GET apps" && len(parts) == 2:
+		s.getApp(w, parts[1])
+	case route == "GET apps" && len(parts) == 3 && parts[2] == "groups":
+		s.listAppGroups(w, r, parts[1])
+	case route == "GET apps" && len(parts) == 3 && parts[2] == "users":
+		s.listAppUsers(w, r, parts[1])
+	case route == "GET groups" && len(parts) == 1:
+		s.listGroups(w, r)
+	case route == "GET groups" && len(parts) == 2 && parts[1] == "rules":
+		s.listGroupRules(w, r)
+	case route == "GET groups" && len(parts) == 2:
+		s.getGroup(w, parts[1])
+	case route == "GET groups" && len(parts) == 3 && parts[2] == "users":
+		s.listGroupUsers(w, r, parts[1])
+	case (route == "PUT groups" || route == "DELETE groups") && len(parts) == 4 && parts[2] == "users":
+		s.changeMembership(w, r.Method == http.MethodPut, parts[1], parts[3])
+	case route == "GET users" && len(parts) == 1:
+		s.listUsers(w, r)
+	case route == "GET users" && len(parts) == 2:
+		s.getUser(w, parts[1])
+	case route == "GET users" && len(parts) == 3 && parts[2] == "groups":
+		s.listUserGroups(w, r, parts[1])
+	case route == "DELETE users" && len(parts) == 3 && parts[2] == "sessions":
+		s.clearSessions(w, parts[1])
+	case route == "POST users" && len(parts) == 4 && parts[2] == "lifecycle":
+		s.lifecycle(w, parts[1], parts[3])
+	case route == "GET logs" && len(parts) == 1:
+		s.listLogs(w, r)
+	default:
+		s.writeError(w, http.StatusNotFound, "E0000022", fmt.Sprintf("The endpoint does not support the provided HTTP method: %s %s", r.Method, r.URL.Path))
+	}
+}
+
+// writePage writes the page of items after the request's after cursor, with self and next links.
+func writePage[T any](s *Server, w http.ResponseWriter, r *http.Request, items []T, id func(T) string) {
+	q := r.URL.Query()
+	limit := 200
+	if l, err := strconv.Atoi(q.Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+	if s.MaxPageSize > 0 && limit > s.MaxPageSize {
+		limit = s.MaxPageSize
+	}
+	start := 0
+	if after := q.Get("after"); after != "" {
+		for i, item := range items {
+			if id(item) == after {
+				start = i + 1
+				break
+			}
+		}
+	}
+	end := start + limit
+	if end > len(items) {
+		end = len(items)
+	}
+	page := items[start:end]
+	link := func(q url.Values, rel string) {
+		w.Header().Add("Link", fmt.Sprintf(`<%s%s?%s>; rel="%s"`, s.URL, r.URL.Path, q.Encode(), rel))
+	}
+	link(q, "self")
+	if end < len(items) {
+		next := url.Values{}
+		for k, v := range q {
+			next[k] = v
+		}
+		next.Set("after", id(page[len(page)-1]))
+		next.Set("limit", strconv.Itoa(limit))
+		link(next, "next")
+	}
+	s.writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) userJSON(u *User) map[string]interface{} {
+	var lastLogin interface{}
+	if u.LastLogin != "" {
+		lastLogin = u.LastLogin
+	}
+	return map[string]interface{}{
+		"id":        u.ID,
+		"status":    u.Status,
+		"lastLogin": lastLogin,
+		"profile":   u.Profile,
+	}
+}
+
+func (s *Server) groupJSON(g *Group) map[string]interface{} {
+	j := map[string]interface{}{"id": g.ID, "type": g.Type, "profile": g.Profile}
+	if t, ok := s.updated[g.ID]; ok {
+		j["lastMembershipUpdated"] = t.Format(time.RFC3339)
+	}
+	return j
+}
+
+func appJSON(a *App) map[string]interface{} {
+	return map[string]interface{}{"id": a.ID, "name": a.Name, "label": a.Label, "status": a.Status, "signOnMode": a.SignOnMode}
+}
+
+// assignedUsers returns the IDs of the users assigned to the app, directly or through a group.
+func (s *Server) assignedUsers(a *App) []string {
+	ids := []string{}
+	for _, au := range a.Users {
+		ids = append(ids, au.ID)
+	}
+	for _, ag := range a.Groups {
+		if g := s.group(ag.ID); g != nil {
+			ids = append(ids, g.Members...)
+		}
+	}
+	return ids
+}
+
+func (s *Server) listApps(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	apps := []map[string]interface{}{}
+	for _, a := range s.apps {
+		if prefix := strings.ToLower(q.Get("q")); prefix != "" && !strings.HasPrefix(strings.ToLower(a.Name), prefix) && !strings.HasPrefix(strings.ToLower(a.Label), prefix) {
+			continue
+		}
+		ok, err := matches(q.Get("filter"), func(attr string) ([]string, bool) {
+			switch attr {
+			case "status":
+				return []string{a.Status}, true
+			case "name":
+				return []string{a.Name}, true
+			case "user.id":
+				return s.assignedUsers(a), true
+			}
+			return nil, false
+		})
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "E0000031", err.Error())
+			return
+		}
+		if ok {
+			apps = append(apps, appJSON(a))
+		}
+	}
+	writePage(s, w, r, apps, func(j map[string]interface{}) string { return j["id"].(string) })
+}
+
+func (s *Server) getApp(w http.ResponseWriter, id string) {
+	a := s.app(id)
+	if a == nil {
+		s.notFound(w, id, "AppInstance")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, appJSON(a))
+}
+
+func (s *Server) listAppGroups(w http.ResponseWriter, r *http.Request, id string) {
+	a := s.app(id)
+	if a == nil {
+		s.notFound(w, id, "AppInstance")
+		return
+	}
+	assignments := []map[string]interface{}{}
+	for _, ag := range a.Groups {
+		j := map[string]interface{}{"id": ag.ID, "priority": ag.Priority}
+		if ag.Profile != nil {
+			j["profile"] = ag.Profile
+		}
+		assignments = append(assignments, j)
+	}
+	writePage(s, w, r, assignments, func(j map[string]interface{}) string { return j["id"].(string) })
+}
+
+// listAppUsers lists direct assignments with USER scope and users assigned through a group with GROUP scope.
+func (s *Server) listAppUsers(w http.ResponseWriter, r *http.Request, id string) {
+	a := s.app(id)
+	if a == nil {
+		s.notFound(w, id, "AppInstance")
+		return
+	}
+	scopes := map[string]string{}
+	order := []string{}
+	for _, ag := range a.Groups {
+		if g := s.group(ag.ID); g != nil {
+			for _, m := range g.Members {
+				if _, ok := scopes[m]; !ok {
+					order = append(order, m)
+				}
+				scopes[m] = "GROUP"
+			}
+		}
+	}
+	for _, au := range a.Users {
+		if _, ok := scopes[au.ID]; !ok {
+			order = append(order, au.ID)
+		}
+		scopes[au.ID] = "USER"
+	}
+	sort.Strings(order)
+	expand := r.URL.Query().Get("expand") == "user"
+	appUsers := []map[string]interface{}{}
+	for _, userID := range order {
+		u := s.user(userID)
+		if u == nil {
+			continue
+		}
+		j := map[string]interface{}{"id": u.ID, "scope": scopes[userID], "status": "PROVISIONED", "credentials": map[string]string{"userName": u.Profile.Login}}
+		if expand {
+			j["_embedded"] = map[string]interface{}{"user": s.userJSON(u)}
+		}
+		appUsers = append(appUsers, j)
+	}
+	writePage(s, w, r, appUsers, func(j map[string]interface{}) string { return j["id"].(string) })
+}
+
+func (s *Server) listGroups(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	expr := q.Get("search")
+	if expr == "" {
+		expr = q.Get("filter")
+	}
+	groups := []map[string]interface{}{}
+	for _, g := range s.groups {
+		if prefix := strings.ToLower(q.Get("q")); prefix != "" && !strings.HasPrefix(strings.ToLower(g.Profile.Name), prefix) {
+			continue
+		}
+		ok, err := matches(expr, func(attr string) ([]string, bool) {
+			switch attr {
+			case "id":
+				return []string{g.ID}, true
+			case "type":
+				return []string{g.Type}, true
+			case "profile.name":
+				return []string{g.Profile.Name}, true
+			}
+			return nil, false
+		})
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "E0000031", err.Error())
+			return
+		}
+		if ok {
+			groups = append(groups, s.groupJSON(g))
+		}
+	}
+	writePage(s, w, r, groups, func(j map[string]interface{}) string { return j["id"].(string) })
+}
+
+func (s *Server) getGroup(w http.ResponseWriter, id string) {
+	g := s.group(id)
+	if g == nil {
+		s.notFound(w, id, "UserGroup")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, s.groupJSON(g))
+}
+
+func (s *Server) listGroupUsers(w http.ResponseWriter, r *http.Request, id string) {
+	g := s.group(id)
+	if g == nil {
+		s.notFound(w, id, "UserGroup")
+		return
+	}
+	users := []map[string]interface{}{}
+	for _, m := range g.Members {
+		if u := s.user(m); u != nil {
+			users = append(users, s.userJSON(u))
+		}
+	}
+	writePage(s, w, r, users, func(j map[string]interface{}) string { return j["id"].(string) })
+}
+
+func (s *Server) listGroupRules(w http.ResponseWriter, r *http.Request) {
+	rules := []map[string]interface{}{}
+	for _, rule := range s.rules {
+		rules = append(rules, map[string]interface{}{
+			"id":      rule.ID,
+			"name":    rule.Name,
+			"status":  rule.Status,
+			"type":    "group_rule",
+			"actions": map[string]interface{}{"assignUserToGroups": map[string]interface{}{"groupIds": rule.GroupIDs}},
+		})
+	}
+	writePage(s, w, r, rules, func(j map[string]interface{}) string { return j["id"].(string) })
+}
+
+func (s *Server) changeMembership(w http.ResponseWriter, add bool, groupID, userID string) {
+	g := s.group(groupID)
+	if g == nil {
+		s.notFound(w, groupID, "UserGroup")
+		return
+	}
+	u := s.user(userID)
+	if u == nil {
+		s.notFound(w, userID, "User")
+		return
+	}
+	if g.Type != "OKTA_GROUP" {
+		s.writeError(w, http.StatusForbidden, "E0000006", "You do not have permission to perform the requested action")
+		return
+	}
+	eventType, message := "group.user_membership.add", "Add user to group membership"
+	if add && !contains(g.Members, u.ID) {
+		g.Members = append(g.Members, u.ID)
+	}
+	if !add {
+		eventType, message = "group.user_membership.remove", "Remove user from group membership"
+		members := []string{}
+		for _, m := range g.Members {
+			if m != u.ID {
+				members = append(members, m)
+			}
+		}
+		g.Members = members
+	}
+	s.updated[g.ID] = s.now()
+	s.log(eventType, message, userTarget(u), LogActor{ID: g.ID, Type: "UserGroup", AlternateID: "unknown", DisplayName: g.Profile.Name})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	expr := q.Get("search")
+	if expr == "" {
+		expr = q.Get("filter")
+	}
+	users := []map[string]interface{}{}
+	for _, u := range s.users {
+		if prefix := strings.ToLower(q.Get("q")); prefix != "" && !hasAnyPrefix(prefix, u.Profile.Login, u.Profile.Email, u.Profile.FirstName, u.Profile.LastName) {
+			continue
+		}
+		ok, err := matches(expr, func(attr string) ([]string, bool) {
+			switch attr {
+			case "id":
+				return []string{u.ID}, true
+			case "status":
+				return []string{u.Status}, true
+			case "profile.login":
+				return []string{u.Profile.Login}, true
+			case "profile.email":
+				return []string{u.Profile.Email}, true
+			case "profile.firstName":
+				return []string{u.Profile.FirstName}, true
+			case "profile.lastName":
+				return []string{u.Profile.LastName}, true
+			}
+			return nil, false
+		})
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "E0000031", err.Error())
+			return
+		}
+		if ok {
+			users = append(users, s.userJSON(u))
+		}
+	}
+	writePage(s, w, r, users, func(j map[string]interface{}) string { return j["id"].(string) })
+}
+
+func (s *Server) getUser(w http.ResponseWriter, ref string) {
+	u := s.user(ref)
+	if u == nil {
+		s.notFound(w, ref, "User")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, s.userJSON(u))
+}
+
+func (s *Server) listUserGroups(w http.ResponseWriter, r *http.Request, ref string) {
+	u := s.user(ref)
+	if u == nil {
+		s.notFound(w, ref, "User")
+		return
+	}
+	groups := []map[string]interface{}{}
+	for _, g := range s.groups {
+		if contains(g.Members, u.ID) {
+			groups = append(groups, s.groupJSON(g))
+		}
+	}
+	writePage(s, w, r, groups, func(j map[string]interface{}) string { return j["id"].(string) })
+}
+
+func (s *Server) clearSessions(w http.ResponseWriter, ref string) {
+	u := s.user(ref)
+	if u == nil {
+		s.notFound(w, ref, "User")
+		return
+	}
+	s.sessions[u.ID]++
+	s.log("user.session.clear", "Clear user session", userTarget(u))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// lifecycle moves a user between statuses the way Okta does, rejecting transitions Okta does not allow.
+func (s *Server) lifecycle(w http.ResponseWriter, ref, action string) {
+	u := s.user(ref)
+	if u == nil {
+		s.notFound(w, ref, "User")
+		return
+	}
+	transitions := map[string]struct {
+		from    []string
+		to      string
+		message string
+	}{
+		"suspend":    {[]string{"ACTIVE"}, "SUSPENDED", "Suspend user"},
+		"unsuspend":  {[]string{"SUSPENDED"}, "ACTIVE", "Unsuspend user"},
+		"deactivate": {[]string{"STAGED", "PROVISIONED", "ACTIVE", "RECOVERY", "PASSWORD_EXPIRED", "LOCKED_OUT", "SUSPENDED"}, "DEPROVISIONED", "Deactivate user"},
+		"activate":   {[]string{"STAGED", "DEPROVISIONED"}, "PROVISIONED", "Activate user"},
+	}
+	t, ok := transitions[action]
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "E0000022", "The endpoint does not support the provided HTTP method")
+		return
+	}
+	if !contains(t.from, u.Status) {
+		s.writeError(w, http.StatusBadRequest, "E0000001", fmt.Sprintf("Api validation failed: cannot %s a user with status %s", action, u.Status))
+		return
+	}
+	u.Status = t.to
+	s.log("user.lifecycle."+action, t.message, userTarget(u))
+	if action == "activate" {
+		s.writeJSON(w, http.StatusOK, map[string]string{"activationToken": "fake-activation-token", "activationUrl": s.URL + "/welcome/fake-activation-token"})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) listLogs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var since, until time.Time
+	for name, t := range map[string]*time.Time{"since": &since, "until": &until} {
+		if v := q.Get(name); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				s.writeError(w, http.StatusBadRequest, "E0000001", fmt.Sprintf("Api validation failed: %s is not a valid ISO 8601 timestamp", name))
+				return
+			}
+			*t = parsed
+		}
+	}
+	events := []LogEvent{}
+	for _, e := range s.logs {
+		published, _ := time.Parse(time.RFC3339, e.Published)
+		if (!since.IsZero() && published.Before(since)) || (!until.IsZero() && !published.Before(until)) {
+			continue
+		}
+		ok, err := matches(q.Get("filter"), func(attr string) ([]string, bool) {
+			switch attr {
+			case "eventType":
+				return []string{e.EventType}, true
+			case "actor.id":
+				return []string{e.Actor.ID}, true
+			case "target.id":
+				ids := []string{}
+				for _, t := range e.Target {
+					ids = append(ids, t.ID)
+				}
+				return ids, true
+			}
+			return nil, false
+		})
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "E0000031", err.Error())
+			return
+		}
+		if ok {
+			events = append(events, e)
+		}
+	}
+	writePage(s, w, r, events, func(e LogEvent) string { return e.UUID })
+}
+
+// log records a successful change made through the API.
+func (s *Server) log(eventType, message string, target ...LogActor) {
+	s.logs = append(s.logs, LogEvent{
+		UUID:           s.newID("log"),
+		Published:      s.now().Format(time.RFC3339Nano),
+		EventType:      eventType,
+		DisplayMessage: message,
+		Severity:       "INFO",
+		Outcome:        LogOutcome{Result: "SUCCESS"},
+		Actor:          LogActor{ID: "00ufakeadmin", Type: "User", AlternateID: "admin@fakeokta.test", DisplayName: "Fake Admin"},
+		Target:         target,
+	})
+}
+
+func userTarget(u *User) LogActor {
+	return LogActor{ID: u.ID, Type: "User", AlternateID: u.Profile.Login, DisplayName: strings.TrimSpace(u.Profile.FirstName + " " + u.Profile.LastName)}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyPrefix(prefix string, values ...string) bool {
+	for _, v := range values {
+		if strings.HasPrefix(strings.ToLower(v), prefix) {
+			return true
+		}
+	}
+	return false
+}
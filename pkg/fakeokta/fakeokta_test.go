@@ -0,0 +1,208 @@
+package fakeokta
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newServer(t *testing.T) *Server {
+	t.Helper()
+	seed, err := LoadSeed("testdata/seed.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(seed)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func do(t *testing.T, s *Server, method, path string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, s.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "SSWS "+s.Token)
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func decode(t *testing.T, resp *http.Response) []map[string]interface{} {
+	t.Helper()
+	var items []map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		t.Fatal(err)
+	}
+	return items
+}
+
+func TestSeed(t *testing.T) {
+	s := newServer(t)
+	if got := s.Members("00g1eng0000000000002"); strings.Join(got, ",") != "00u1alex000000000001,00u1bobb000000000002" {
+		t.Errorf("members seeded by login = %v", got)
+	}
+	if u, ok := s.User("cara@example.com"); !ok || u.Status != "SUSPENDED" {
+		t.Errorf("User(cara) = %+v, %v", u, ok)
+	}
+	if _, err := ParseSeed([]byte("users: {")); err == nil {
+		t.Error("expected error parsing invalid YAML")
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	s := newServer(t)
+	resp, err := s.Client().Get(s.URL + "/api/v1/users/alex@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status without token = %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestPagination(t *testing.T) {
+	s := newServer(t)
+	s.MaxPageSize = 2
+	var ids []string
+	path := "/api/v1/groups?limit=100"
+	for pages := 0; path != ""; pages++ {
+		if pages > 5 {
+			t.Fatal("pagination did not end")
+		}
+		resp := do(t, s, http.MethodGet, path)
+		for _, g := range decode(t, resp) {
+			ids = append(ids, g["id"].(string))
+		}
+		path = ""
+		for _, link := range resp.Header.Values("Link") {
+			if strings.HasSuffix(link, `rel="next"`) {
+				path = strings.TrimPrefix(strings.Split(link, ">")[0], "<"+s.URL)
+			}
+		}
+	}
+	if len(ids) != 4 {
+		t.Errorf("paged through %v, want 4 groups", ids)
+	}
+}
+
+func TestFilters(t *testing.T) {
+	s := newServer(t)
+	tests := []struct {
+		path string
+		want int
+	}{
+		{`/api/v1/groups?search=profile.name+sw+"eng"`, 1},
+		{`/api/v1/groups?search=type+eq+"OKTA_GROUP"`, 3},
+		{`/api/v1/groups?q=Super`, 1},
+		{`/api/v1/apps?filter=status+eq+"ACTIVE"`, 2},
+		{`/api/v1/apps?filter=user.id+eq+"00u1cara000000000003"`, 2},
+		{`/api/v1/apps?filter=user.id+eq+"00u1bobb000000000002"`, 2},
+		{`/api/v1/apps?q=aws`, 1},
+		{`/api/v1/users?search=profile.email+eq+"bob@example.com"`, 1},
+		{`/api/v1/users?q=c`, 1},
+		{`/api/v1/users/00u1alex000000000001/groups`, 3},
+		{`/api/v1/apps/0oa1aws0000000000001/groups`, 2},
+		{`/api/v1/logs?filter=eventType+eq+"user.session.start"`, 1},
+	}
+	for _, tt := range tests {
+		resp := do(t, s, http.MethodGet, tt.path)
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: status %d", tt.path, resp.StatusCode)
+			continue
+		}
+		if got := len(decode(t, resp)); got != tt.want {
+			t.Errorf("%s: got %d items, want %d", tt.path, got, tt.want)
+		}
+	}
+	if resp := do(t, s, http.MethodGet, `/api/v1/groups?search=profile.name+co+"eng"`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unsupported operator: status %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestAppUsersScope(t *testing.T) {
+	s := newServer(t)
+	scopes := map[string]string{}
+	for _, u := range decode(t, do(t, s, http.MethodGet, "/api/v1/apps/0oa1aws0000000000001/users?expand=user")) {
+		scopes[u["id"].(string)] = u["scope"].(string)
+		if _, ok := u["_embedded"]; !ok {
+			t.Errorf("user %s is missing _embedded", u["id"])
+		}
+	}
+	want := map[string]string{"00u1alex000000000001": "GROUP", "00u1bobb000000000002": "GROUP", "00u1cara000000000003": "USER"}
+	if fmt.Sprint(scopes) != fmt.Sprint(want) {
+		t.Errorf("scopes = %v, want %v", scopes, want)
+	}
+}
+
+func TestWrites(t *testing.T) {
+	s := newServer(t)
+	if resp := do(t, s, http.MethodPut, "/api/v1/groups/00g1admins0000000001/users/00u1bobb000000000002"); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("add member: status %d", resp.StatusCode)
+	}
+	if resp := do(t, s, http.MethodPut, "/api/v1/groups/00g0everyone00000000/users/00u1bobb000000000002"); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("add to built-in group: status %d, want 403", resp.StatusCode)
+	}
+	if resp := do(t, s, http.MethodDelete, "/api/v1/groups/00g1admins0000000001/users/00u1alex000000000001"); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("remove member: status %d", resp.StatusCode)
+	}
+	if got := s.Members("00g1admins0000000001"); len(got) != 1 || got[0] != "00u1bobb000000000002" {
+		t.Errorf("admins = %v", got)
+	}
+	if resp := do(t, s, http.MethodPost, "/api/v1/users/00u1bobb000000000002/lifecycle/unsuspend"); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unsuspend active user: status %d, want 400", resp.StatusCode)
+	}
+	for _, action := range []string{"suspend", "unsuspend", "deactivate", "activate"} {
+		if resp := do(t, s, http.MethodPost, "/api/v1/users/00u1bobb000000000002/lifecycle/"+action); resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: status %d", action, resp.StatusCode)
+		}
+	}
+	if u, _ := s.User("00u1bobb000000000002"); u.Status != "PROVISIONED" {
+		t.Errorf("status after activate = %s", u.Status)
+	}
+	do(t, s, http.MethodDelete, "/api/v1/users/00u1bobb000000000002/sessions?oauthTokens=true")
+	if s.Sessions("00u1bobb000000000002") != 1 {
+		t.Error("sessions were not cleared")
+	}
+	var types []string
+	for _, e := range s.Logs() {
+		types = append(types, e.EventType)
+	}
+	want := "user.session.start,group.user_membership.add,group.user_membership.remove,user.lifecycle.suspend,user.lifecycle.unsuspend,user.lifecycle.deactivate,user.lifecycle.activate,user.session.clear"
+	if strings.Join(types, ",") != want {
+		t.Errorf("logged %v", types)
+	}
+	if resp := do(t, s, http.MethodGet, "/api/v1/logs?filter=target.id+eq+\"00g1admins0000000001\""); len(decode(t, resp)) != 2 {
+		t.Error("expected two events targeting the admins group")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	s := newServer(t)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.Now = func() time.Time { return now }
+	s.RateLimit = 2
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		resp := do(t, s, http.MethodGet, "/api/v1/users/alex@example.com")
+		io.Copy(io.Discard, resp.Body)
+		if resp.StatusCode != want {
+			t.Errorf("request %d: status %d, want %d", i+1, resp.StatusCode, want)
+		}
+		if resp.Header.Get("X-Rate-Limit-Limit") != "2" || resp.Header.Get("X-Rate-Limit-Reset") != fmt.Sprint(now.Add(time.Minute).Unix()) {
+			t.Errorf("request %d: rate limit headers %v", i+1, resp.Header)
+		}
+	}
+	now = now.Add(time.Minute)
+	if resp := do(t, s, http.MethodGet, "/api/v1/users/alex@example.com"); resp.StatusCode != http.StatusOK || resp.Header.Get("X-Rate-Limit-Remaining") != "1" {
+		t.Errorf("new window: status %d, remaining %s", resp.StatusCode, resp.Header.Get("X-Rate-Limit-Remaining"))
+	}
+}
@@ -0,0 +1,44 @@
+package fakeokta
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	clause  = regexp.MustCompile(`^\s*([A-Za-z_.]+)\s+(eq|sw)\s+"((?:[^"\\]|\\.)*)"\s*$`)
+	escaped = regexp.MustCompile(`\\(.)`)
+)
+
+// matches evaluates the subset of Okta's filter and search syntax that oktactl uses:
+// `attr eq "value"` and `attr sw "value"` clauses joined by "and". An empty expression
+// matches everything. values returns an attribute's values and whether it is supported;
+// a clause matches if any value does.
+func matches(expr string, values func(attr string) ([]string, bool)) (bool, error) {
+	if strings.TrimSpace(expr) == "" {
+		return true, nil
+	}
+	for _, c := range regexp.MustCompile(`(?i)\s+and\s+`).Split(expr, -1) {
+		m := clause.FindStringSubmatch(c)
+		if m == nil {
+			return false, fmt.Errorf("invalid search criteria: unsupported expression %q", c)
+		}
+		attr, op, want := m[1], m[2], escaped.ReplaceAllString(m[3], "$1")
+		got, ok := values(attr)
+		if !ok {
+			return false, fmt.Errorf("invalid search criteria: unsupported attribute %q", attr)
+		}
+		matched := false
+		for _, v := range got {
+			if (op == "eq" && v == want) || (op == "sw" && strings.HasPrefix(strings.ToLower(v), strings.ToLower(want))) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,113 @@
+package fakeokta
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Seed is the initial state of a fake org.
+type Seed struct {
+	Users      []User      `yaml:"users"`
+	Groups     []Group     `yaml:"groups"`
+	Apps       []App       `yaml:"apps"`
+	GroupRules []GroupRule `yaml:"groupRules"`
+	Logs       []LogEvent  `yaml:"logs"`
+}
+
+// User is an Okta user. Status defaults to ACTIVE.
+type User struct {
+	ID        string      `yaml:"id"`
+	Status    string      `yaml:"status"`
+	LastLogin string      `yaml:"lastLogin"`
+	Profile   UserProfile `yaml:"profile"`
+}
+
+type UserProfile struct {
+	Login     string `yaml:"login" json:"login"`
+	Email     string `yaml:"email" json:"email"`
+	FirstName string `yaml:"firstName" json:"firstName"`
+	LastName  string `yaml:"lastName" json:"lastName"`
+}
+
+// Group is an Okta group. Members lists user IDs or logins. Type defaults to OKTA_GROUP.
+type Group struct {
+	ID      string       `yaml:"id"`
+	Type    string       `yaml:"type"`
+	Profile GroupProfile `yaml:"profile"`
+	Members []string     `yaml:"members"`
+}
+
+type GroupProfile struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description,omitempty"`
+}
+
+// App is an Okta app with its group and direct user assignments. Status defaults to ACTIVE.
+type App struct {
+	ID         string          `yaml:"id"`
+	Name       string          `yaml:"name"`
+	Label      string          `yaml:"label"`
+	Status     string          `yaml:"status"`
+	SignOnMode string          `yaml:"signOnMode"`
+	Groups     []AppAssignment `yaml:"groups"`
+	Users      []AppAssignment `yaml:"users"`
+}
+
+// AppAssignment assigns a group or user, by ID, to an app.
+type AppAssignment struct {
+	ID       string                 `yaml:"id"`
+	Priority int                    `yaml:"priority"`
+	Profile  map[string]interface{} `yaml:"profile"`
+}
+
+// GroupRule assigns users to GroupIDs. Status defaults to ACTIVE.
+type GroupRule struct {
+	ID       string   `yaml:"id"`
+	Name     string   `yaml:"name"`
+	Status   string   `yaml:"status"`
+	GroupIDs []string `yaml:"groupIds"`
+}
+
+// LogEvent is a System Log event.
+type LogEvent struct {
+	UUID           string     `yaml:"uuid" json:"uuid"`
+	Published      string     `yaml:"published" json:"published"`
+	EventType      string     `yaml:"eventType" json:"eventType"`
+	DisplayMessage string     `yaml:"displayMessage" json:"displayMessage"`
+	Severity       string     `yaml:"severity" json:"severity"`
+	Outcome        LogOutcome `yaml:"outcome" json:"outcome"`
+	Actor          LogActor   `yaml:"actor" json:"actor"`
+	Target         []LogActor `yaml:"target" json:"target"`
+}
+
+type LogOutcome struct {
+	Result string `yaml:"result" json:"result"`
+}
+
+// LogActor is the actor or a target of a LogEvent.
+type LogActor struct {
+	ID          string `yaml:"id" json:"id"`
+	Type        string `yaml:"type" json:"type"`
+	AlternateID string `yaml:"alternateId" json:"alternateId"`
+	DisplayName string `yaml:"displayName" json:"displayName"`
+}
+
+// ParseSeed reads a seed from YAML.
+func ParseSeed(b []byte) (*Seed, error) {
+	seed := &Seed{}
+	if err := yaml.Unmarshal(b, seed); err != nil {
+		return nil, fmt.Errorf("parsing seed: %w", err)
+	}
+	return seed, nil
+}
+
+// LoadSeed reads a seed from a YAML file.
+func LoadSeed(path string) (*Seed, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSeed(b)
+}
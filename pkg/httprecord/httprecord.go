@@ -0,0 +1,377 @@
+// Package httprecord records HTTP exchanges with the Okta API to fixture files and replays them.
+//
+// Recorded fixtures are safe to share: API tokens and cookies are never written, the org's
+// address is replaced with ReplayOrg, and personal data such as logins, emails, names and
+// phone numbers is replaced with pseudonyms. A pseudonym is derived from the value with a key
+// that lives only as long as the Recorder, so the same person gets the same pseudonym
+// throughout one recording, including in request URLs, and developers can replay a command
+// by passing the pseudonym instead of the real login.
+package httprecord
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ReplayOrg is the org URL written into fixtures in place of the recorded org.
+const ReplayOrg = "https://example.okta.com"
+
+// keptResponseHeaders are the response headers written to fixtures. The SDK needs Link for
+// pagination and Date with the rate limit headers to back off; the rest are dropped.
+var keptResponseHeaders = []string{"Content-Type", "Date", "Link", "X-Okta-Request-Id", "X-Rate-Limit-Limit", "X-Rate-Limit-Remaining", "X-Rate-Limit-Reset"}
+
+// Fixture is one recorded request and its response.
+type Fixture struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type Request struct {
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+type Response struct {
+	Status  int                 `json:"status"`
+	Headers map[string][]string `json:"headers"`
+	Body    json.RawMessage     `json:"body,omitempty"`
+	// Text holds a body that is not JSON.
+	Text string `json:"text,omitempty"`
+}
+
+// Recorder is an http.RoundTripper that sends requests with Base and writes every exchange
+// to a numbered fixture file in Dir.
+type Recorder struct {
+	Dir  string
+	Base http.RoundTripper
+
+	mu  sync.Mutex
+	n   int
+	key []byte
+}
+
+// NewRecorder returns a Recorder writing to dir, creating it if needed.
+func NewRecorder(dir string, base http.RoundTripper) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	existing, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Recorder{Dir: dir, Base: base, n: len(existing), key: key}, nil
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+	resp, err := r.Base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	red := &redactor{key: r.key, org: req.URL.Scheme + "://" + req.URL.Host}
+	f := Fixture{
+		Request:  Request{Method: req.Method, URL: red.url(req.URL), Body: red.json(reqBody)},
+		Response: Response{Status: resp.StatusCode, Headers: map[string][]string{}},
+	}
+	for _, h := range keptResponseHeaders {
+		for _, v := range resp.Header.Values(h) {
+			f.Response.Headers[h] = append(f.Response.Headers[h], red.text(v))
+		}
+	}
+	if len(respBody) > 0 {
+		if json.Valid(respBody) {
+			f.Response.Body = red.json(respBody)
+		} else {
+			f.Response.Text = red.text(string(respBody))
+		}
+	}
+	if err := r.write(f); err != nil {
+		return nil, fmt.Errorf("recording %s %s: %w", req.Method, f.Request.URL, err)
+	}
+	return resp, nil
+}
+
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+func (r *Recorder) write(f Fixture) error {
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.n++
+	n := r.n
+	r.mu.Unlock()
+	path := strings.SplitN(f.Request.URL, "?", 2)[0]
+	name := strings.Trim(unsafeNameChars.ReplaceAllString(strings.TrimPrefix(path, "/api/v1/"), "_"), "_")
+	if len(name) > 60 {
+		name = name[:60]
+	}
+	return os.WriteFile(filepath.Join(r.Dir, fmt.Sprintf("%04d-%s-%s.json", n, f.Request.Method, name)), append(b, '\n'), 0o644)
+}
+
+// Replayer is an http.RoundTripper that answers requests from recorded fixtures without
+// touching the network. Requests are matched on method and URL path and query. Repeated
+// requests get the recorded responses in order, and the last one once those run out, so
+// that reads after a write see the recorded change.
+type Replayer struct {
+	mu        sync.Mutex
+	responses map[string][]Response
+}
+
+// NewReplayer loads every fixture in dir.
+func NewReplayer(dir string) (*Replayer, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no fixtures in %s", dir)
+	}
+	sort.Strings(paths)
+	r := &Replayer{responses: map[string][]Response{}}
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var f Fixture
+		if err := json.Unmarshal(b, &f); err != nil {
+			return nil, fmt.Errorf("reading fixture %s: %w", path, err)
+		}
+		u, err := url.Parse(f.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("reading fixture %s: %w", path, err)
+		}
+		k := key(f.Request.Method, u)
+		r.responses[k] = append(r.responses[k], f.Response)
+	}
+	return r, nil
+}
+
+// key identifies a request by method, path and query with its parameters sorted.
+func key(method string, u *url.URL) string {
+	return method + " " + u.EscapedPath() + "?" + u.Query().Encode()
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	k := key(req.Method, req.URL)
+	r.mu.Lock()
+	queue := r.responses[k]
+	if len(queue) == 0 {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("no recorded response for %s %s", req.Method, req.URL.RequestURI())
+	}
+	rec := queue[0]
+	if len(queue) > 1 {
+		r.responses[k] = queue[1:]
+	}
+	r.mu.Unlock()
+
+	body := []byte(rec.Text)
+	if len(rec.Body) > 0 {
+		body = rec.Body
+	}
+	header := http.Header{}
+	for k, vs := range rec.Headers {
+		for _, v := range vs {
+			header.Add(k, strings.ReplaceAll(v, ReplayOrg, req.URL.Scheme+"://"+req.URL.Host))
+		}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status)),
+		StatusCode:    rec.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// piiFields are JSON keys whose string values identify a person.
+var piiFields = map[string]bool{
+	"login": true, "email": true, "secondEmail": true, "alternateId": true, "userName": true,
+	"firstName": true, "lastName": true, "middleName": true, "displayName": true, "nickName": true,
+	"honorificPrefix": true, "honorificSuffix": true, "title": true,
+	"mobilePhone": true, "primaryPhone": true, "streetAddress": true, "city": true, "zipCode": true, "postalAddress": true,
+	"employeeNumber": true, "manager": true, "managerId": true,
+	"ipAddress": true, "ip": true, "rawUserAgent": true,
+}
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+type redactor struct {
+	key []byte
+	org string
+}
+
+func (r *redactor) hash(v string) string {
+	m := hmac.New(sha256.New, r.key)
+	m.Write([]byte(strings.ToLower(v)))
+	return hex.EncodeToString(m.Sum(nil))[:10]
+}
+
+// pseudonym replaces a personal value, keeping email addresses shaped like email addresses.
+func (r *redactor) pseudonym(v string) string {
+	if v == "" {
+		return v
+	}
+	if emailPattern.MatchString(v) {
+		return emailPattern.ReplaceAllStringFunc(v, func(email string) string {
+			return "user-" + r.hash(email) + "@example.com"
+		})
+	}
+	return "redacted-" + r.hash(v)
+}
+
+// text replaces the org address and any email addresses in s.
+func (r *redactor) text(s string) string {
+	s = strings.ReplaceAll(s, r.org, ReplayOrg)
+	return emailPattern.ReplaceAllStringFunc(s, r.pseudonym)
+}
+
+// url redacts a request URL down to its path and query. A user can be named by login in
+// the path, /api/v1/users/{login}, and by any profile value in a search or filter expression,
+// so those are replaced whatever their shape; IDs and the values of keptAttributes stay so
+// that replayed commands send the same requests.
+func (r *redactor) url(u *url.URL) string {
+	segs := strings.Split(u.EscapedPath(), "/")
+	for i, seg := range segs {
+		if i > 0 && segs[i-1] == "users" && seg != "me" && !userID.MatchString(seg) {
+			if v, err := url.PathUnescape(seg); err == nil {
+				seg = url.PathEscape(r.pseudonym(v))
+			} else {
+				seg = r.pseudonym(seg)
+			}
+		}
+		segs[i] = emailPattern.ReplaceAllStringFunc(seg, r.pseudonym)
+	}
+	path := strings.Join(segs, "/")
+	users := strings.HasPrefix(u.Path, "/api/v1/users")
+	q := u.Query()
+	for k, vs := range q {
+		for i, v := range vs {
+			switch {
+			case k == "search" || k == "filter":
+				vs[i] = r.expression(v, users)
+			case k == "q" && users:
+				vs[i] = r.pseudonym(v)
+			default:
+				vs[i] = r.text(v)
+			}
+		}
+		q[k] = vs
+	}
+	if len(q) == 0 {
+		return path
+	}
+	return path + "?" + q.Encode()
+}
+
+// userID matches the IDs Okta gives users.
+var userID = regexp.MustCompile(`^00u[0-9A-Za-z]{17}$`)
+
+// comparison matches one comparison in an Okta search or filter expression, such as
+// profile.login eq "jdoe".
+var comparison = regexp.MustCompile(`([A-Za-z0-9_.$]+)(\s+(?:eq|ne|sw|co|ew|gt|ge|lt|le)\s+)"((?:[^"\\]|\\.)*)"`)
+
+// keptAttributes are attributes whose values in an expression never identify a person,
+// including IDs such as user.id.
+var keptAttributes = map[string]bool{
+	"id": true, "status": true, "type": true, "created": true, "lastUpdated": true,
+	"statusChanged": true, "activated": true, "lastLogin": true, "passwordChanged": true,
+}
+
+// expression redacts the values compared in a search or filter expression. Group and app
+// names are kept, except in expressions over users, whose profile.name is a person's.
+func (r *redactor) expression(expr string, users bool) string {
+	return comparison.ReplaceAllStringFunc(expr, func(m string) string {
+		parts := comparison.FindStringSubmatch(m)
+		attr, op, v := parts[1], parts[2], parts[3]
+		name := attr[strings.LastIndex(attr, ".")+1:]
+		if keptAttributes[name] || (!users && attr == "profile.name") {
+			return r.text(m)
+		}
+		return attr + op + `"` + r.pseudonym(v) + `"`
+	})
+}
+
+// json redacts a JSON document. Invalid JSON is dropped rather than risk writing it unredacted.
+func (r *redactor) json(b []byte) json.RawMessage {
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return json.RawMessage(`"[unredactable body removed]"`)
+	}
+	out, err := json.Marshal(r.value("", v))
+	if err != nil {
+		return json.RawMessage(`"[unredactable body removed]"`)
+	}
+	return out
+}
+
+func (r *redactor) value(field string, v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			v[k] = r.value(k, child)
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = r.value(field, child)
+		}
+		return v
+	case string:
+		if piiFields[field] {
+			return r.pseudonym(v)
+		}
+		return r.text(v)
+	}
+	return v
+}
@@ -0,0 +1,176 @@
+package httprecord
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flynshue/oktactl/pkg/fakeokta"
+	"github.com/flynshue/oktactl/pkg/okta-api/v2/oktaapi"
+	"github.com/okta/okta-sdk-golang/v2/okta"
+)
+
+func TestRecordReplay(t *testing.T) {
+	seed, err := fakeokta.LoadSeed("../fakeokta/testdata/seed.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := fakeokta.New(seed)
+	defer srv.Close()
+	srv.MaxPageSize = 1
+	dir := t.TempDir()
+
+	rec, err := NewRecorder(dir, srv.Client().Transport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := oktaapi.NewClient(srv.URL, srv.Token, okta.WithTestingDisableHttpsCheck(true), okta.WithCache(false), okta.WithHttpClientPtr(&http.Client{Transport: rec}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	alex, err := client.GetUserById("alex@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded, err := client.ListOktaGroupUsers("00g1eng0000000000002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.RemoveOktaGroupUser("00g1eng0000000000002", alex.ID); err != nil {
+		t.Fatal(err)
+	}
+	after, err := client.ListOktaGroupUsers("00g1eng0000000000002")
+	if err != nil || len(after) != 1 {
+		t.Fatalf("members after removal = %v, %v", after, err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	// One user lookup, two pages of members, the removal and one page after it.
+	if len(files) != 5 {
+		t.Errorf("recorded %d fixtures, want 5", len(files))
+	}
+	var pseudonym string
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, secret := range []string{srv.Token, "alex@example.com", "bob@example.com", "Alex", "Builder", srv.URL} {
+			if strings.Contains(string(b), secret) {
+				t.Errorf("%s contains %q", filepath.Base(f), secret)
+			}
+		}
+		if strings.HasPrefix(filepath.Base(f), "0001-") {
+			var fixture Fixture
+			if err := json.Unmarshal(b, &fixture); err != nil {
+				t.Fatal(err)
+			}
+			pseudonym = strings.TrimPrefix(fixture.Request.URL, "/api/v1/users/")
+		}
+	}
+	if pseudonym == "" {
+		t.Fatalf("user lookup fixture not found in %v", files)
+	}
+
+	replayer, err := NewReplayer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay, err := oktaapi.NewClient(ReplayOrg, "replay", okta.WithCache(false), okta.WithHttpClientPtr(&http.Client{Transport: replayer}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The login in the recorded URL was replaced, so the replayed command uses the pseudonym.
+	user, err := replay.GetUserById(pseudonym)
+	if err != nil || user.ID != alex.ID || user.Email == alex.Email || !strings.HasSuffix(user.Email, "@example.com") {
+		t.Fatalf("replayed user = %+v, %v", user, err)
+	}
+	members, err := replay.ListOktaGroupUsers("00g1eng0000000000002")
+	if err != nil || len(members) != len(recorded) || members[0].ID != recorded[0].ID || members[0].Email != user.Email {
+		t.Errorf("replayed members = %+v, %v", members, err)
+	}
+	if err := replay.RemoveOktaGroupUser("00g1eng0000000000002", alex.ID); err != nil {
+		t.Fatal(err)
+	}
+	if members, err := replay.ListOktaGroupUsers("00g1eng0000000000002"); err != nil || len(members) != 1 {
+		t.Errorf("replayed members after removal = %+v, %v", members, err)
+	}
+	if _, err := replay.ListOktaGroupUsers("00g1admins0000000001"); err == nil || !strings.Contains(err.Error(), "no recorded response for GET /api/v1/groups/00g1admins0000000001/users") {
+		t.Errorf("unrecorded request error = %v", err)
+	}
+}
+
+func TestRedactor(t *testing.T) {
+	r := &redactor{key: []byte("k"), org: "https://acme.okta.com"}
+	got := string(r.json([]byte(`{"profile":{"login":"Alex@Acme.com","firstName":"Alex","department":"Eng"},"_links":{"self":{"href":"https://acme.okta.com/api/v1/users/00u1"}},"note":"ping alex@acme.com"}`)))
+	for _, leaked := range []string{"Alex", "acme"} {
+		if strings.Contains(got, leaked) {
+			t.Errorf("redacted JSON %s contains %q", got, leaked)
+		}
+	}
+	if !strings.Contains(got, `"department":"Eng"`) || !strings.Contains(got, ReplayOrg+"/api/v1/users/00u1") {
+		t.Errorf("redacted JSON %s lost non-personal data", got)
+	}
+	if r.pseudonym("alex@acme.com") != r.pseudonym("Alex@Acme.com") {
+		t.Error("pseudonyms differ by case")
+	}
+	if got := string(r.json([]byte("not json"))); strings.Contains(got, "not json") {
+		t.Errorf("invalid JSON was written: %s", got)
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	r := &redactor{key: []byte("k"), org: "https://acme.okta.com"}
+	redact := func(raw string) string {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r.url(u)
+	}
+	for _, raw := range []string{
+		"https://acme.okta.com/api/v1/users/jdoe",
+		"https://acme.okta.com/api/v1/users/jdoe/lifecycle/activate?sendEmail=false",
+		`https://acme.okta.com/api/v1/users?search=profile.login+eq+"jdoe"`,
+		`https://acme.okta.com/api/v1/users?filter=profile.nickName+sw+"jdoe"&limit=200`,
+		"https://acme.okta.com/api/v1/users?q=jdoe",
+	} {
+		if got := redact(raw); strings.Contains(got, "jdoe") {
+			t.Errorf("url(%s) = %s", raw, got)
+		}
+	}
+	if a, b := redact("https://acme.okta.com/api/v1/users/jdoe"), redact(`https://acme.okta.com/api/v1/users?search=profile.login+eq+"jdoe"`); !strings.Contains(b, url.QueryEscape(`"`+strings.TrimPrefix(a, "/api/v1/users/")+`"`)) {
+		t.Errorf("pseudonyms differ between %s and %s", a, b)
+	}
+	for raw, want := range map[string]string{
+		"https://acme.okta.com/api/v1/users/00u1alex000000000001/groups":             "/api/v1/users/00u1alex000000000001/groups",
+		"https://acme.okta.com/api/v1/users/me":                                      "/api/v1/users/me",
+		`https://acme.okta.com/api/v1/apps?filter=user.id+eq+"00u1alex000000000001"`: "/api/v1/apps?filter=" + url.QueryEscape(`user.id eq "00u1alex000000000001"`),
+		`https://acme.okta.com/api/v1/apps?filter=status+eq+"ACTIVE"&q=Wiki`:         "/api/v1/apps?filter=" + url.QueryEscape(`status eq "ACTIVE"`) + "&q=Wiki",
+		`https://acme.okta.com/api/v1/groups?search=profile.name+sw+"Eng"`:           "/api/v1/groups?search=" + url.QueryEscape(`profile.name sw "Eng"`),
+		`https://acme.okta.com/api/v1/users?search=status+eq+"SUSPENDED"`:            "/api/v1/users?search=" + url.QueryEscape(`status eq "SUSPENDED"`),
+	} {
+		if got := redact(raw); got != want {
+			t.Errorf("url(%s) = %s, want %s", raw, got, want)
+		}
+	}
+
+	dir := t.TempDir()
+	rec, err := NewRecorder(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.write(Fixture{Request: Request{Method: http.MethodGet, URL: redact("https://acme.okta.com/api/v1/users/jdoe")}}); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	for _, f := range files {
+		if strings.Contains(f, "jdoe") {
+			t.Errorf("fixture file %s names the user", filepath.Base(f))
+		}
+	}
+}
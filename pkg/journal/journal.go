@@ -0,0 +1,216 @@
+// Package journal records every change oktactl makes to an org in a local
+// append-only file, together with the change that undoes it.
+package journal
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Operation types recorded in the journal.
+const (
+	AddGroupUser    = "group.user.add"
+	RemoveGroupUser = "group.user.remove"
+	ClearSessions   = "user.sessions.clear"
+	SuspendUser     = "user.suspend"
+	UnsuspendUser   = "user.unsuspend"
+	DeactivateUser  = "user.deactivate"
+	ActivateUser    = "user.activate"
+)
+
+var inverses = map[string]string{
+	AddGroupUser:    RemoveGroupUser,
+	RemoveGroupUser: AddGroupUser,
+	SuspendUser:     UnsuspendUser,
+	UnsuspendUser:   SuspendUser,
+	DeactivateUser:  ActivateUser,
+	ActivateUser:    DeactivateUser,
+}
+
+// Op is a single change to the org.
+type Op struct {
+	Type    string `json:"type"`
+	GroupID string `json:"groupId,omitempty"`
+	UserID  string `json:"userId,omitempty"`
+}
+
+// Inverse returns the change that undoes op, or nil if op cannot be undone.
+func (op Op) Inverse() *Op {
+	t, ok := inverses[op.Type]
+	if !ok {
+		return nil
+	}
+	return &Op{Type: t, GroupID: op.GroupID, UserID: op.UserID}
+}
+
+func (op Op) String() string {
+	if op.GroupID != "" {
+		return fmt.Sprintf("%s group=%s user=%s", op.Type, op.GroupID, op.UserID)
+	}
+	return fmt.Sprintf("%s user=%s", op.Type, op.UserID)
+}
+
+// Entry is one line of the journal. Every change made by a single command
+// run shares the same ID so the whole run can be rolled back at once.
+type Entry struct {
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	Command    string    `json:"command"`
+	RollbackOf string    `json:"rollbackOf,omitempty"`
+	Op         Op        `json:"op"`
+	Inverse    *Op       `json:"inverse,omitempty"`
+	// Done marks the end of a rollback: it is written once every change of RollbackOf has
+	// been undone, and records no change itself.
+	Done bool `json:"done,omitempty"`
+}
+
+// Operation summarises the entries that share an ID. An operation is only RolledBackBy a
+// rollback that finished; one that stopped partway is IncompleteRollbackBy, and can be resumed.
+type Operation struct {
+	ID                   string
+	Started              time.Time
+	Command              string
+	RollbackOf           string
+	Changes              int
+	RolledBackBy         string
+	IncompleteRollbackBy string
+}
+
+// Journal is an append-only file of entries, one JSON object per line.
+type Journal struct {
+	path string
+}
+
+// Open returns the journal stored at path. The file is created on the first Append.
+func Open(path string) *Journal {
+	return &Journal{path: path}
+}
+
+// DefaultPath is the journal location used when none is configured.
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".oktactl", "journal.jsonl"), nil
+}
+
+// NewID returns a new operation ID that sorts by creation time.
+func NewID() string {
+	b := make([]byte, 3)
+	rand.Read(b)
+	return time.Now().UTC().Format("20060102T150405") + "-" + hex.EncodeToString(b)
+}
+
+// Append writes e to the end of the journal.
+func (j *Journal) Append(e Entry) error {
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o700); err != nil {
+		return err
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Entries returns every entry in the order it was written. A missing journal has no entries.
+func (j *Journal) Entries() ([]Entry, error) {
+	f, err := os.Open(j.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []Entry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries := []Entry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		e := Entry{}
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", j.path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// Operation returns the entries recorded for operation id.
+func (j *Journal) Operation(id string) ([]Entry, error) {
+	entries, err := j.Entries()
+	if err != nil {
+		return nil, err
+	}
+	op := []Entry{}
+	for _, e := range entries {
+		if e.ID == id {
+			op = append(op, e)
+		}
+	}
+	if len(op) == 0 {
+		return nil, fmt.Errorf("operation %s not found in journal %s", id, j.path)
+	}
+	return op, nil
+}
+
+// Operations summarises the journal by operation, oldest first.
+func (j *Journal) Operations() ([]Operation, error) {
+	entries, err := j.Entries()
+	if err != nil {
+		return nil, err
+	}
+	ops := []Operation{}
+	index := map[string]int{}
+	done := map[string]bool{}
+	for _, e := range entries {
+		i, ok := index[e.ID]
+		if !ok {
+			i = len(ops)
+			index[e.ID] = i
+			ops = append(ops, Operation{ID: e.ID, Started: e.Time, Command: e.Command, RollbackOf: e.RollbackOf})
+		}
+		if e.Done {
+			done[e.ID] = true
+			continue
+		}
+		ops[i].Changes++
+	}
+	for _, op := range ops {
+		if op.RollbackOf == "" {
+			continue
+		}
+		if i, ok := index[op.RollbackOf]; ok {
+			if done[op.ID] {
+				ops[i].RolledBackBy, ops[i].IncompleteRollbackBy = op.ID, ""
+			} else if ops[i].RolledBackBy == "" {
+				ops[i].IncompleteRollbackBy = op.ID
+			}
+		}
+	}
+	return ops, nil
+}
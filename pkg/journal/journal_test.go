@@ -0,0 +1,75 @@
+package journal
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournal(t *testing.T) {
+	j := Open(filepath.Join(t.TempDir(), "oktactl", "journal.jsonl"))
+	entries, err := j.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("new journal has %d entries", len(entries))
+	}
+	add := Op{Type: AddGroupUser, GroupID: "00g1emaKYZTWRYYRRTSK", UserID: "00u1"}
+	clear := Op{Type: ClearSessions, UserID: "00u1"}
+	for _, e := range []Entry{
+		{ID: "op1", Time: time.Now(), Command: "oktactl apply", Op: add, Inverse: add.Inverse()},
+		{ID: "op1", Time: time.Now(), Command: "oktactl apply", Op: clear, Inverse: clear.Inverse()},
+		{ID: "op2", Time: time.Now(), Command: "oktactl journal rollback op1", RollbackOf: "op1", Op: *add.Inverse()},
+	} {
+		if err := j.Append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	op1, err := j.Operation("op1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(op1) != 2 || op1[0].Inverse.Type != RemoveGroupUser || op1[1].Inverse != nil {
+		t.Errorf("unexpected op1 entries %+v", op1)
+	}
+	if _, err := j.Operation("missing"); err == nil {
+		t.Error("expected error for missing operation")
+	}
+	ops, err := j.Operations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 2 || ops[0].Changes != 2 || ops[0].IncompleteRollbackBy != "op2" || ops[0].RolledBackBy != "" || ops[1].RollbackOf != "op1" {
+		t.Errorf("unexpected operations before the rollback is done %+v", ops)
+	}
+	if err := j.Append(Entry{ID: "op2", Time: time.Now(), Command: "oktactl journal rollback op1", RollbackOf: "op1", Done: true}); err != nil {
+		t.Fatal(err)
+	}
+	ops, err = j.Operations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 2 || ops[0].RolledBackBy != "op2" || ops[0].IncompleteRollbackBy != "" || ops[1].Changes != 1 {
+		t.Errorf("unexpected operations after the rollback is done %+v", ops)
+	}
+}
+
+func TestOpInverse(t *testing.T) {
+	for op, want := range map[string]string{
+		AddGroupUser:   RemoveGroupUser,
+		SuspendUser:    UnsuspendUser,
+		DeactivateUser: ActivateUser,
+	} {
+		inv := Op{Type: op, UserID: "00u1"}.Inverse()
+		if inv == nil || inv.Type != want || inv.UserID != "00u1" {
+			t.Errorf("inverse of %s = %v, want %s", op, inv, want)
+		}
+		if back := inv.Inverse(); back == nil || back.Type != op {
+			t.Errorf("inverse of %s = %v, want %s", want, back, op)
+		}
+	}
+	if inv := (Op{Type: ClearSessions}).Inverse(); inv != nil {
+		t.Errorf("inverse of %s = %v, want nil", ClearSessions, inv)
+	}
+}
@@ -0,0 +1,210 @@
+// Package notify sends membership change events to webhooks such as Slack incoming webhooks.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/flynshue/oktactl/pkg/watch"
+)
+
+// Sink formats.
+const (
+	FormatWebhook = "webhook"
+	FormatSlack   = "slack"
+)
+
+// DefaultTemplate posts the event itself as the JSON payload.
+const DefaultTemplate = `{{json .}}`
+
+// SlackTemplate is the payload sent to Slack-compatible incoming webhooks.
+const SlackTemplate = `{"text": {{json (printf "oktactl: %s was %s %s %s %s" (or .MemberName .MemberID) .Action (preposition .Action) .Resource .ResourceID)}}}`
+
+// SinkConfig configures a webhook that events can be sent to.
+type SinkConfig struct {
+	Name string `mapstructure:"name"`
+	// Format is webhook or slack. It defaults to webhook.
+	Format string `mapstructure:"format"`
+	URL    string `mapstructure:"url"`
+	// Template is a text/template rendering the JSON payload of a webhook sink from a watch.Event.
+	// The json function encodes a value as JSON. It defaults to DefaultTemplate.
+	Template string            `mapstructure:"template"`
+	Headers  map[string]string `mapstructure:"headers"`
+}
+
+// RouteConfig sends the events of one group or app to the named sinks.
+type RouteConfig struct {
+	Group string   `mapstructure:"group"`
+	App   string   `mapstructure:"app"`
+	Sinks []string `mapstructure:"sinks"`
+}
+
+// Config is the notify section of .oktactl.yaml.
+type Config struct {
+	Sinks  []SinkConfig  `mapstructure:"sinks"`
+	Routes []RouteConfig `mapstructure:"routes"`
+}
+
+// Webhook posts a JSON payload rendered from each event to a URL.
+type Webhook struct {
+	Name     string
+	URL      string
+	Headers  map[string]string
+	Template *template.Template
+	Client   *http.Client
+}
+
+var funcs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"preposition": func(action string) string {
+		if action == watch.Removed {
+			return "from"
+		}
+		return "to"
+	},
+}
+
+// NewWebhook returns the sink described by c.
+func NewWebhook(c SinkConfig, client *http.Client) (*Webhook, error) {
+	if c.URL == "" {
+		return nil, fmt.Errorf("sink %s: url is required", c.Name)
+	}
+	text := c.Template
+	switch c.Format {
+	case "", FormatWebhook:
+		if text == "" {
+			text = DefaultTemplate
+		}
+	case FormatSlack:
+		if text != "" {
+			return nil, fmt.Errorf("sink %s: template cannot be set for slack sinks", c.Name)
+		}
+		text = SlackTemplate
+	default:
+		return nil, fmt.Errorf("sink %s: unsupported format %q, must be one of webhook, slack", c.Name, c.Format)
+	}
+	tmpl, err := template.New(c.Name).Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("sink %s: %w", c.Name, err)
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Webhook{Name: c.Name, URL: c.URL, Headers: c.Headers, Template: tmpl, Client: client}, nil
+}
+
+// Payload renders the JSON payload for e.
+func (w *Webhook) Payload(e watch.Event) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := w.Template.Execute(buf, e); err != nil {
+		return nil, err
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("sink %s: template did not render valid JSON: %s", w.Name, buf.String())
+	}
+	return buf.Bytes(), nil
+}
+
+// Send posts e to the webhook. Any status other than 2xx is an error.
+func (w *Webhook) Send(ctx context.Context, e watch.Event) error {
+	payload, err := w.Payload(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sink %s: %w", w.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("sink %s: %s: %s", w.Name, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// Notifier sends events to the sinks routed to their group or app.
+type Notifier struct {
+	sinks  map[string]*Webhook
+	routes map[string][]*Webhook
+}
+
+// New builds a Notifier from c. Routes must name configured sinks.
+func New(c Config, client *http.Client) (*Notifier, error) {
+	n := &Notifier{sinks: map[string]*Webhook{}, routes: map[string][]*Webhook{}}
+	for _, sc := range c.Sinks {
+		if sc.Name == "" {
+			return nil, fmt.Errorf("every sink must have a name")
+		}
+		if _, ok := n.sinks[sc.Name]; ok {
+			return nil, fmt.Errorf("sink %s is configured more than once", sc.Name)
+		}
+		sink, err := NewWebhook(sc, client)
+		if err != nil {
+			return nil, err
+		}
+		n.sinks[sc.Name] = sink
+	}
+	for _, r := range c.Routes {
+		key, err := routeKey(r)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range r.Sinks {
+			sink, ok := n.sinks[name]
+			if !ok {
+				return nil, fmt.Errorf("route for %s: unknown sink %s", key, name)
+			}
+			n.routes[key] = append(n.routes[key], sink)
+		}
+	}
+	return n, nil
+}
+
+func routeKey(r RouteConfig) (string, error) {
+	switch {
+	case r.Group != "" && r.App != "":
+		return "", fmt.Errorf("route sets both group %s and app %s", r.Group, r.App)
+	case r.Group != "":
+		return watch.Group + "/" + r.Group, nil
+	case r.App != "":
+		return watch.App + "/" + r.App, nil
+	}
+	return "", fmt.Errorf("route must set group or app")
+}
+
+// Routed reports whether any sink receives events for the resource.
+func (n *Notifier) Routed(resource, id string) bool {
+	return len(n.routes[resource+"/"+id]) > 0
+}
+
+// Notify sends e to every sink routed to its resource. Every sink is tried;
+// the errors of those that failed are joined.
+func (n *Notifier) Notify(ctx context.Context, e watch.Event) error {
+	var errs []error
+	for _, sink := range n.routes[e.Resource+"/"+e.ResourceID] {
+		if err := sink.Send(ctx, e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,123 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flynshue/oktactl/pkg/watch"
+)
+
+var event = watch.Event{
+	Time:       time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+	Resource:   watch.Group,
+	ResourceID: "00g1emaKYZTWRYYRRTSK",
+	Action:     watch.Added,
+	MemberID:   "00u1",
+	MemberName: `alex "the admin"@example.com`,
+}
+
+// recorder stands in for a webhook receiver and keeps every request body.
+type recorder struct {
+	status  int
+	bodies  []string
+	headers []http.Header
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	b, _ := io.ReadAll(req.Body)
+	r.bodies = append(r.bodies, string(b))
+	r.headers = append(r.headers, req.Header)
+	w.WriteHeader(r.status)
+}
+
+func TestNotify(t *testing.T) {
+	hook := &recorder{status: http.StatusOK}
+	slack := &recorder{status: http.StatusOK}
+	hookSrv := httptest.NewServer(hook)
+	defer hookSrv.Close()
+	slackSrv := httptest.NewServer(slack)
+	defer slackSrv.Close()
+
+	n, err := New(Config{
+		Sinks: []SinkConfig{
+			{Name: "siem", URL: hookSrv.URL, Headers: map[string]string{"Authorization": "Bearer s3cret"}},
+			{Name: "custom", URL: hookSrv.URL, Template: `{"who": {{json .MemberName}}, "what": {{json .Action}}}`},
+			{Name: "security", Format: FormatSlack, URL: slackSrv.URL},
+		},
+		Routes: []RouteConfig{
+			{Group: "00g1emaKYZTWRYYRRTSK", Sinks: []string{"siem", "custom", "security"}},
+			{App: "0oa1gjh63g214q0Hq0g4", Sinks: []string{"security"}},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !n.Routed(watch.Group, "00g1emaKYZTWRYYRRTSK") || n.Routed(watch.Group, "00gother") {
+		t.Error("Routed does not match the configured routes")
+	}
+	if err := n.Notify(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	if len(hook.bodies) != 2 {
+		t.Fatalf("webhook received %d requests, want 2", len(hook.bodies))
+	}
+	var got watch.Event
+	if err := json.Unmarshal([]byte(hook.bodies[0]), &got); err != nil || got != event {
+		t.Errorf("default payload = %s (%v)", hook.bodies[0], err)
+	}
+	if hook.headers[0].Get("Authorization") != "Bearer s3cret" || hook.headers[0].Get("Content-Type") != "application/json" {
+		t.Errorf("headers = %v", hook.headers[0])
+	}
+	if want := `{"who": "alex \"the admin\"@example.com", "what": "added"}`; hook.bodies[1] != want {
+		t.Errorf("templated payload = %s, want %s", hook.bodies[1], want)
+	}
+	if want := `{"text": "oktactl: alex \"the admin\"@example.com was added to group 00g1emaKYZTWRYYRRTSK"}`; len(slack.bodies) != 1 || slack.bodies[0] != want {
+		t.Errorf("slack payloads = %v, want %s", slack.bodies, want)
+	}
+
+	other := event
+	other.ResourceID = "00gother"
+	if err := n.Notify(context.Background(), other); err != nil || len(hook.bodies) != 2 {
+		t.Errorf("unrouted event was sent: %v", err)
+	}
+}
+
+func TestNotifyErrors(t *testing.T) {
+	srv := httptest.NewServer(&recorder{status: http.StatusInternalServerError})
+	defer srv.Close()
+	n, err := New(Config{
+		Sinks:  []SinkConfig{{Name: "down", URL: srv.URL}, {Name: "bad", URL: srv.URL, Template: `{{.MemberID}}`}},
+		Routes: []RouteConfig{{Group: event.ResourceID, Sinks: []string{"down", "bad"}}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = n.Notify(context.Background(), event)
+	if err == nil || !strings.Contains(err.Error(), "sink down: 500") || !strings.Contains(err.Error(), "sink bad: template did not render valid JSON") {
+		t.Errorf("Notify error = %v", err)
+	}
+}
+
+func TestNewConfigErrors(t *testing.T) {
+	tests := map[string]Config{
+		"missing url":      {Sinks: []SinkConfig{{Name: "a"}}},
+		"unknown format":   {Sinks: []SinkConfig{{Name: "a", URL: "http://x", Format: "teams"}}},
+		"slack template":   {Sinks: []SinkConfig{{Name: "a", URL: "http://x", Format: FormatSlack, Template: "{}"}}},
+		"duplicate sink":   {Sinks: []SinkConfig{{Name: "a", URL: "http://x"}, {Name: "a", URL: "http://y"}}},
+		"unknown sink":     {Routes: []RouteConfig{{Group: "00g1", Sinks: []string{"a"}}}},
+		"no target":        {Routes: []RouteConfig{{Sinks: []string{"a"}}}},
+		"group and app":    {Routes: []RouteConfig{{Group: "00g1", App: "0oa1"}}},
+		"invalid template": {Sinks: []SinkConfig{{Name: "a", URL: "http://x", Template: "{{"}}},
+	}
+	for name, c := range tests {
+		if _, err := New(c, nil); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package oktaapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/okta/okta-sdk-golang/v2/okta/query"
+)
+
+const benchPageSize = 200
+
+// benchUsersPage returns page i of the members of a large group, as Okta returns them.
+func benchUsersPage(i int) []byte {
+	users := make([]map[string]interface{}, benchPageSize)
+	for j := range users {
+		n := i*benchPageSize + j
+		users[j] = map[string]interface{}{
+			"id":        fmt.Sprintf("00u1bench%011d", n),
+			"status":    "ACTIVE",
+			"created":   "2023-01-05T10:00:00.000Z",
+			"lastLogin": "2024-03-01T10:00:00.000Z",
+			"profile": map[string]string{
+				"login":     fmt.Sprintf("user%d@example.com", n),
+				"email":     fmt.Sprintf("user%d@example.com", n),
+				"firstName": "Bench",
+				"lastName":  fmt.Sprintf("User %d", n),
+			},
+			"_links": map[string]interface{}{"self": map[string]string{"href": fmt.Sprintf("https://example.okta.com/api/v1/users/00u1bench%011d", n)}},
+		}
+	}
+	b, err := json.Marshal(users)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// newLargeGroupServer returns a server that lists a group of pages*200 members from pages
+// rendered up front, so that the benchmarks measure the clients only.
+func newLargeGroupServer(b *testing.B, pages int) *httptest.Server {
+	b.Helper()
+	bodies := make([][]byte, pages)
+	for i := range bodies {
+		bodies[i] = benchUsersPage(i)
+	}
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("after"))
+		w.Header().Set("Content-Type", "application/json")
+		if page+1 < pages {
+			w.Header().Add("Link", fmt.Sprintf(`<%s%s?after=%d&limit=%d>; rel="next"`, srv.URL, r.URL.Path, page+1, benchPageSize))
+		}
+		w.Write(bodies[page])
+	}))
+	b.Cleanup(srv.Close)
+	return srv
+}
+
+// sdkListGroupUsers lists a group's members the way the client did before it sent requests
+// itself: through the SDK's group service, which decodes every page into its own types, then
+// reading each page's body again to decode it into ours.
+func sdkListGroupUsers(ctx context.Context, client *okta.Client, groupID string) ([]User, error) {
+	_, resp, err := client.Group.ListGroupUsers(ctx, groupID, query.NewQueryParams(query.WithLimit(benchPageSize)))
+	users := []User{}
+	for {
+		if err != nil {
+			return nil, err
+		}
+		b, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		page := []User{}
+		if err := json.Unmarshal(b, &page); err != nil {
+			return nil, err
+		}
+		users = append(users, page...)
+		if !resp.HasNextPage() {
+			return users, nil
+		}
+		var sdk []*okta.User
+		resp, err = resp.Next(ctx, &sdk)
+	}
+}
+
+// BenchmarkListGroupUsers compares listing the members of a large group through the SDK's
+// group service with the client's own typed requests.
+func BenchmarkListGroupUsers(b *testing.B) {
+	const groupID = "00g1large00000000001"
+	for _, pages := range []int{1, 50} {
+		srv := newLargeGroupServer(b, pages)
+		opts := []okta.ConfigSetter{okta.WithTestingDisableHttpsCheck(true), okta.WithCache(false), okta.WithHttpClientPtr(srv.Client())}
+		b.Run(fmt.Sprintf("members=%d/sdk", pages*benchPageSize), func(b *testing.B) {
+			ctx, client, err := okta.NewClient(context.Background(), append([]okta.ConfigSetter{okta.WithOrgUrl(srv.URL), okta.WithToken("bench-token")}, opts...)...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				users, err := sdkListGroupUsers(ctx, client, groupID)
+				if err != nil || len(users) != pages*benchPageSize {
+					b.Fatalf("sdkListGroupUsers = %d users, %v", len(users), err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("members=%d/typed", pages*benchPageSize), func(b *testing.B) {
+			client, err := NewClient(srv.URL, "bench-token", opts...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				users, err := client.ListOktaGroupUsers(groupID)
+				if err != nil || len(users) != pages*benchPageSize {
+					b.Fatalf("ListOktaGroupUsers = %d users, %v", len(users), err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package oktaapi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheTransport is an http.RoundTripper that keeps successful GET responses on disk and
+// answers repeated GETs from them until TTL passes, so that commands run one after another
+// do not fetch the same groups and users again. Entries are kept per org, in a directory
+// named after the org's host, with one file per request URL.
+//
+// Any other request is sent as usual and then drops the cached entries it may have changed:
+// those whose path or query contains an ID from the request's path, and listings of a kind
+// named in it. Adding a user to a group, PUT /api/v1/groups/{groupId}/users/{userId}, drops
+// the group, the user, the apps listed for the user with filter=user.id eq "{userId}", and
+// every cached list of groups or users.
+type CacheTransport struct {
+	Dir  string
+	TTL  time.Duration
+	Base http.RoundTripper
+	// Refresh sends every GET to Base instead of answering it from the cache, for commands
+	// that must act on the org as it is now. Responses are still cached for other commands.
+	Refresh bool
+
+	now   func() time.Time
+	prune sync.Once
+}
+
+// NewCacheTransport returns a CacheTransport keeping entries in dir for ttl. base sends the
+// requests that are not answered from the cache, http.DefaultTransport if nil.
+func NewCacheTransport(dir string, ttl time.Duration, base http.RoundTripper) *CacheTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &CacheTransport{Dir: dir, TTL: ttl, Base: base, now: time.Now}
+}
+
+type cachedResponse struct {
+	URL     string              `json:"url"`
+	Fetched time.Time           `json:"fetched"`
+	Status  int                 `json:"status"`
+	Headers map[string][]string `json:"headers"`
+	Body    []byte              `json:"body"`
+}
+
+func (c *CacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		resp, err := c.Base.RoundTrip(req)
+		c.invalidate(req)
+		return resp, err
+	}
+	file := c.file(req)
+	if cached, ok := c.read(file); ok && !c.Refresh && cached.URL == req.URL.String() && c.now().Sub(cached.Fetched) < c.TTL {
+		return &http.Response{
+			Status:        http.StatusText(cached.Status),
+			StatusCode:    cached.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header(cached.Headers),
+			Body:          io.NopCloser(bytes.NewReader(cached.Body)),
+			ContentLength: int64(len(cached.Body)),
+			Request:       req,
+		}, nil
+	}
+	resp, err := c.Base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	headers := resp.Header.Clone()
+	headers.Del("Set-Cookie")
+	// Failing to write the cache only costs speed on the next run, so the response is
+	// returned either way.
+	c.write(file, cachedResponse{URL: req.URL.String(), Fetched: c.now(), Status: resp.StatusCode, Headers: headers, Body: body})
+	return resp, nil
+}
+
+func (c *CacheTransport) orgDir(req *http.Request) string {
+	return filepath.Join(c.Dir, strings.ReplaceAll(req.URL.Host, ":", "_"))
+}
+
+func (c *CacheTransport) file(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.URL.String()))
+	return filepath.Join(c.orgDir(req), hex.EncodeToString(sum[:16])+".json")
+}
+
+func (c *CacheTransport) read(file string) (cachedResponse, bool) {
+	cached := cachedResponse{}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return cached, false
+	}
+	return cached, json.Unmarshal(b, &cached) == nil
+}
+
+func (c *CacheTransport) write(file string, cached cachedResponse) error {
+	c.prune.Do(func() {
+		c.removeEntries(filepath.Dir(file), func(e cachedResponse) bool { return c.now().Sub(e.Fetched) >= c.TTL })
+	})
+	b, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return err
+	}
+	// Write to a temporary file first so that a concurrent run never reads half an entry.
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+var resourceID = regexp.MustCompile(`^[0-9A-Za-z]{20}$`)
+
+// invalidate drops the entries a write to req's path may have changed.
+func (c *CacheTransport) invalidate(req *http.Request) {
+	ids, kinds := []string{}, map[string]bool{}
+	for _, seg := range strings.Split(strings.Trim(req.URL.Path, "/"), "/") {
+		if resourceID.MatchString(seg) {
+			ids = append(ids, seg)
+		} else if seg != "" {
+			kinds[seg] = true
+		}
+	}
+	c.removeEntries(c.orgDir(req), func(e cachedResponse) bool {
+		u, err := url.Parse(e.URL)
+		if err != nil {
+			return true
+		}
+		query, err := url.QueryUnescape(u.RawQuery)
+		if err != nil {
+			query = u.RawQuery
+		}
+		for _, id := range ids {
+			if strings.Contains(u.Path+"/", "/"+id+"/") || strings.Contains(query, id) {
+				return true
+			}
+		}
+		return kinds[path.Base(u.Path)]
+	})
+}
+
+// removeEntries removes the entries in dir that drop reports true for.
+func (c *CacheTransport) removeEntries(dir string, drop func(cachedResponse) bool) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		if cached, ok := c.read(file); !ok || drop(cached) {
+			os.Remove(file)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package oktaapi
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/flynshue/oktactl/pkg/fakeokta"
+	"github.com/okta/okta-sdk-golang/v2/okta"
+)
+
+type countingTransport struct {
+	base http.RoundTripper
+	n    atomic.Int32
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.n.Add(1)
+	return c.base.RoundTrip(req)
+}
+
+func TestCacheTransport(t *testing.T) {
+	seed, err := fakeokta.LoadSeed("../../../fakeokta/testdata/seed.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := fakeokta.New(seed)
+	t.Cleanup(srv.Close)
+	counter := &countingTransport{base: srv.Client().Transport}
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	cache := NewCacheTransport(t.TempDir(), 5*time.Minute, counter)
+	cache.now = func() time.Time { return now }
+	// The SDK's own cache is off so that every request reaches the disk cache.
+	client, err := NewClient(srv.URL, srv.Token, append(srv.Options(), okta.WithCache(false), okta.WithHttpClientPtr(&http.Client{Transport: cache}))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := func() int { return int(counter.n.Swap(0)) }
+
+	const eng = "00g1eng0000000000002"
+	for i := 0; i < 2; i++ {
+		if users, err := client.ListOktaGroupUsers(eng); err != nil || len(users) != 2 {
+			t.Fatalf("ListOktaGroupUsers = %v, %v", users, err)
+		}
+		if _, err := client.ListApps(""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := requests(); n != 2 {
+		t.Errorf("sent %d requests for two lists read twice, want 2", n)
+	}
+
+	if err := client.AddOktaGroupUser(eng, "00u1cara000000000003"); err != nil {
+		t.Fatal(err)
+	}
+	requests()
+	if users, err := client.ListOktaGroupUsers(eng); err != nil || len(users) != 3 {
+		t.Errorf("ListOktaGroupUsers after adding a member = %v, %v", users, err)
+	}
+	if _, err := client.ListApps(""); err != nil {
+		t.Fatal(err)
+	}
+	if n := requests(); n != 1 {
+		t.Errorf("sent %d requests after the write, want 1 for the changed group only", n)
+	}
+
+	// The apps listed for a user are found by a filter on the user's ID, which a change to
+	// the user's groups may change.
+	const cara = "00u1cara000000000003"
+	for i := 0; i < 2; i++ {
+		if _, err := client.ListOktaUserApps(cara); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := client.RemoveOktaGroupUser(eng, cara); err != nil {
+		t.Fatal(err)
+	}
+	requests()
+	if _, err := client.ListOktaUserApps(cara); err != nil {
+		t.Fatal(err)
+	}
+	if n := requests(); n != 1 {
+		t.Errorf("sent %d requests for the user's apps after a membership change, want 1", n)
+	}
+
+	cache.Refresh = true
+	if _, err := client.ListApps(""); err != nil {
+		t.Fatal(err)
+	}
+	if n := requests(); n != 1 {
+		t.Errorf("sent %d requests for a cached entry when refreshing, want 1", n)
+	}
+	cache.Refresh = false
+
+	now = now.Add(5 * time.Minute)
+	if _, err := client.ListApps(""); err != nil {
+		t.Fatal(err)
+	}
+	if n := requests(); n != 1 {
+		t.Errorf("sent %d requests for an expired entry, want 1", n)
+	}
+}
@@ -0,0 +1,221 @@
+package oktaapi
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flynshue/oktactl/pkg/fakeokta"
+	"github.com/okta/okta-sdk-golang/v2/okta"
+)
+
+// newFakeClient returns a client talking to a fakeokta server seeded from its testdata,
+// with pages of one item so that every list follows pagination links. The SDK's response
+// cache is off, since the tests read back what they change and count requests.
+func newFakeClient(t *testing.T) (*OktaClient, *fakeokta.Server) {
+	t.Helper()
+	seed, err := fakeokta.LoadSeed("../../../fakeokta/testdata/seed.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := fakeokta.New(seed)
+	t.Cleanup(srv.Close)
+	srv.MaxPageSize = 1
+	client, err := NewClient(srv.URL, srv.Token, append(srv.Options(), okta.WithCache(false))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, srv
+}
+
+func TestE2EReads(t *testing.T) {
+	client, _ := newFakeClient(t)
+	apps, err := client.ListApps("")
+	if err != nil || len(apps) != 2 {
+		t.Fatalf("ListApps = %v, %v", apps, err)
+	}
+	app, assignments, err := client.ListAppsGroups("0oa1aws0000000000001")
+	if err != nil || app.Label != "AWS Prod" || len(assignments) != 2 {
+		t.Fatalf("ListAppsGroups = %+v, %+v, %v", app, assignments, err)
+	}
+	if assignments[0].Name != "Super Admins" || assignments[0].Role != "admin" || assignments[1].SAMLRoles[0] != "ReadOnly" {
+		t.Errorf("assignments = %+v", assignments)
+	}
+	appUsers, err := client.ListAppUsers("0oa1aws0000000000001")
+	if err != nil || len(appUsers) != 3 || appUsers[2].Scope != "USER" || appUsers[2].Embedded.User.Email != "cara@example.com" {
+		t.Errorf("ListAppUsers = %+v, %v", appUsers, err)
+	}
+	if app, err := client.GetAppById("0oa1gone000000000009"); !IsNotFound(err) || app.ID != "" {
+		t.Errorf("GetAppById(unknown) = %+v, %v, want not found", app, err)
+	}
+	if _, _, err := client.ListAppsGroups("0oa1gone000000000009"); !IsNotFound(err) {
+		t.Errorf("ListAppsGroups(unknown) error = %v, want not found", err)
+	}
+	groups, err := client.ListOktaGroups("Eng")
+	if err != nil || len(groups) != 1 || groups[0].ID != "00g1eng0000000000002" {
+		t.Errorf("ListOktaGroups(Eng) = %+v, %v", groups, err)
+	}
+	if groups, err := client.ListOktaGroups(`Eng" or type eq "\`); err != nil || len(groups) != 0 {
+		t.Errorf("ListOktaGroups(quoted) = %+v, %v, want no groups", groups, err)
+	}
+	members, err := client.ListOktaGroupUsers("00g1eng0000000000002")
+	if err != nil || len(members) != 2 || members[0].LastLogin == "" {
+		t.Errorf("ListOktaGroupUsers = %+v, %v", members, err)
+	}
+	user, err := client.GetUserById("bob@example.com")
+	if err != nil || user.ID != "00u1bobb000000000002" {
+		t.Errorf("GetUserById = %+v, %v", user, err)
+	}
+	if _, err := client.GetUserById("nobody@example.com"); err == nil {
+		t.Error("expected error for unknown user")
+	}
+	userGroups, err := client.ListOktaUserGroups(user.ID)
+	if err != nil || len(userGroups) != 2 {
+		t.Errorf("ListOktaUserGroups = %+v, %v", userGroups, err)
+	}
+	userApps, err := client.ListOktaUserApps("00u1cara000000000003")
+	if err != nil || len(userApps) != 2 {
+		t.Errorf("ListOktaUserApps = %+v, %v", userApps, err)
+	}
+	rules, err := client.ListOktaGroupRules()
+	if err != nil || len(rules) != 1 || rules[0].Actions.AssignUserToGroups.GroupIDs[0] != "00g1eng0000000000002" {
+		t.Errorf("ListOktaGroupRules = %+v, %v", rules, err)
+	}
+	snap, err := client.Snapshot()
+	if err != nil || len(snap.Groups) != 4 || len(snap.GroupUsers["00g0everyone00000000"]) != 3 {
+		t.Errorf("Snapshot = %+v, %v", snap, err)
+	}
+}
+
+func TestE2EResolve(t *testing.T) {
+	client, _ := newFakeClient(t)
+	if id, err := ResolveGroupID(client, "engineering"); err != nil || id != "00g1eng0000000000002" {
+		t.Errorf("ResolveGroupID(engineering) = %s, %v", id, err)
+	}
+	if id, err := ResolveAppID(client, "wiki"); err != nil || id != "0oa1wiki000000000002" {
+		t.Errorf("ResolveAppID(wiki) = %s, %v", id, err)
+	}
+	users, err := client.ListOktaUsers(`profile.email eq "cara@example.com"`)
+	if err != nil || len(users) != 1 || users[0].ID != "00u1cara000000000003" {
+		t.Errorf("ListOktaUsers = %+v, %v", users, err)
+	}
+}
+
+func TestE2EWrites(t *testing.T) {
+	client, srv := newFakeClient(t)
+	if err := client.AddOktaGroupUser("00g1admins0000000001", "00u1bobb000000000002"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.RemoveOktaGroupUser("00g1admins0000000001", "00u1alex000000000001"); err != nil {
+		t.Fatal(err)
+	}
+	members, err := client.ListOktaGroupUsers("00g1admins0000000001")
+	if err != nil || len(members) != 1 || members[0].ID != "00u1bobb000000000002" {
+		t.Errorf("admins after changes = %+v, %v", members, err)
+	}
+	if err := client.AddOktaGroupUser("00g0everyone00000000", "00u1bobb000000000002"); err == nil {
+		t.Error("expected error adding a member to a built-in group")
+	}
+
+	bob := "00u1bobb000000000002"
+	if err := client.ClearOktaUserSessions(bob); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.UnsuspendOktaUser(bob); err == nil {
+		t.Error("expected error unsuspending an active user")
+	}
+	for _, step := range []func(string) error{client.SuspendOktaUser, client.UnsuspendOktaUser, client.DeactivateOktaUser, client.ActivateOktaUser} {
+		if err := step(bob); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if u, _ := srv.User(bob); u.Status != "PROVISIONED" || srv.Sessions(bob) != 1 {
+		t.Errorf("bob = %+v, sessions cleared %d times", u, srv.Sessions(bob))
+	}
+}
+
+func TestE2ERateLimitRetry(t *testing.T) {
+	client, srv := newFakeClient(t)
+	srv.RateLimit = 1
+	srv.RateLimitWindow = time.Second
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetUserById("alex@example.com"); err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+	}
+	if time.Since(start) < 500*time.Millisecond {
+		t.Error("the second request was not rate limited")
+	}
+}
+
+func TestE2EIterGroupUsers(t *testing.T) {
+	_, srv := newFakeClient(t)
+	counter := &countingTransport{base: srv.Client().Transport}
+	client, err := NewClient(srv.URL, srv.Token, append(srv.Options(), okta.WithCache(false), okta.WithHttpClientPtr(&http.Client{Transport: counter}))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	logins := []string{}
+	for u, err := range client.IterGroupUsers(ctx, "00g0everyone00000000") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		logins = append(logins, u.Login)
+	}
+	if got := strings.Join(logins, ","); got != "alex@example.com,bob@example.com,cara@example.com" || counter.n.Load() != 3 {
+		t.Errorf("IterGroupUsers = %s in %d requests, want 3 users in pages of one", got, counter.n.Load())
+	}
+
+	counter.n.Store(0)
+	for range client.IterGroupUsers(ctx, "00g0everyone00000000") {
+		break
+	}
+	if n := counter.n.Load(); n != 1 {
+		t.Errorf("stopping after the first user sent %d requests, want 1", n)
+	}
+
+	n := 0
+	for _, err := range client.IterGroupUsers(ctx, "00gmissing0000000000") {
+		if err == nil {
+			t.Fatal("expected error for a missing group")
+		}
+		n++
+	}
+	if n != 1 {
+		t.Errorf("yielded %d errors, want 1", n)
+	}
+}
+
+// TestE2EPollWithDefaults polls a group twice with the SDK's defaults, as the commands that
+// only read do, and with its response cache off, as the watch commands do.
+func TestE2EPollWithDefaults(t *testing.T) {
+	admin, srv := newFakeClient(t)
+	cached, err := NewClient(srv.URL, srv.Token, srv.Options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uncached, err := NewClient(srv.URL, srv.Token, append(srv.Options(), okta.WithCache(false))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const contractors = "00g1contract00000003"
+	for _, client := range []*OktaClient{cached, uncached} {
+		if users, err := client.ListOktaGroupUsers(contractors); err != nil || len(users) != 1 {
+			t.Fatalf("first poll = %v, %v", users, err)
+		}
+	}
+	if err := admin.AddOktaGroupUser(contractors, "00u1bobb000000000002"); err != nil {
+		t.Fatal(err)
+	}
+	// The SDK keeps responses for five minutes, so a poll with its defaults misses the change.
+	if users, err := cached.ListOktaGroupUsers(contractors); err != nil || len(users) != 1 {
+		t.Errorf("second poll with the SDK's cache = %v, %v, want the cached member only", users, err)
+	}
+	if users, err := uncached.ListOktaGroupUsers(contractors); err != nil || len(users) != 2 {
+		t.Errorf("second poll without the SDK's cache = %v, %v, want 2 members", users, err)
+	}
+}
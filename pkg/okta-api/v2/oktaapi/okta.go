@@ -2,24 +2,22 @@ package oktaapi
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"io"
+	"errors"
+	"iter"
+	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/okta/okta-sdk-golang/v2/okta"
 	"github.com/okta/okta-sdk-golang/v2/okta/query"
 )
 
-type OktaAppService interface {
-	ListApplications(ctx context.Context, qp *query.Params) ([]okta.App, *okta.Response, error)
-	ListApplicationGroupAssignments(ctx context.Context, appID string, qp *query.Params) ([]*okta.ApplicationGroupAssignment, *okta.Response, error)
-	GetApplication(ctx context.Context, appId string, appInstance okta.App, qp *query.Params) (okta.App, *okta.Response, error)
-}
-
-type OktaGroupService interface {
-	GetGroup(ctx context.Context, groupId string) (*okta.Group, *okta.Response, error)
-	ListGroups(ctx context.Context, qp *query.Params) ([]*okta.Group, *okta.Response, error)
-	ListGroupUsers(ctx context.Context, groupId string, qp *query.Params) ([]*okta.User, *okta.Response, error)
+// Requester builds and sends requests to the Okta API, decoding JSON responses into v.
+// okta.RequestExecutor implements it, with authentication, retries when rate limited and the
+// SDK's response cache.
+type Requester interface {
+	NewRequest(method string, url string, body interface{}) (*http.Request, error)
+	Do(ctx context.Context, req *http.Request, v interface{}) (*okta.Response, error)
 }
 
 type App struct {
@@ -29,8 +27,10 @@ type App struct {
 }
 
 type User struct {
-	ID      string `json:"id"`
-	Profile `json:"profile,omitempty"`
+	ID        string `json:"id"`
+	Status    string `json:"status,omitempty"`
+	LastLogin string `json:"lastLogin,omitempty"`
+	Profile   `json:"profile,omitempty"`
 }
 
 type Group struct {
@@ -41,10 +41,33 @@ type Group struct {
 	Profile               `json:"profile,omitempty"`
 }
 
+// AppUser is a user assigned to an app, either directly (scope USER) or through a group (scope GROUP).
+type AppUser struct {
+	ID       string `json:"id"`
+	Scope    string `json:"scope"`
+	Status   string `json:"status,omitempty"`
+	Embedded struct {
+		User User `json:"user"`
+	} `json:"_embedded,omitempty"`
+}
+
 type GroupAssignmentResp struct {
-	GroupID string `json:"id"`
-	Name    string `json:"name,omitempty"`
-	Profile `json:"profile,omitempty"`
+	GroupID  string `json:"id"`
+	Name     string `json:"name,omitempty"`
+	Priority int    `json:"priority"`
+	Profile  `json:"profile,omitempty"`
+}
+
+// GroupRule adds users that match its expression to the groups in Actions.
+type GroupRule struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Actions struct {
+		AssignUserToGroups struct {
+			GroupIDs []string `json:"groupIds"`
+		} `json:"assignUserToGroups"`
+	} `json:"actions"`
 }
 
 type Profile struct {
@@ -52,41 +75,120 @@ type Profile struct {
 	Description string   `json:"description,omitempty"`
 	SAMLRoles   []string `json:"samlRoles,omitempty"`
 	Role        string   `json:"role,omitempty"`
+	Login       string   `json:"login,omitempty"`
 	Email       string   `json:"email,omitempty"`
 	FirstName   string   `json:"firstName,omitempty"`
 	LastName    string   `json:"lastName,omitempty"`
 }
 
+// OktaClient reads and changes an org through the Okta API. Every response is decoded once,
+// straight into the types of this package.
 type OktaClient struct {
-	OktaAppService
-	OktaGroupService
+	API Requester
 	Ctx context.Context
 }
 
-func NewClient(url, token string) (*OktaClient, error) {
-	ctx, client, err := okta.NewClient(context.Background(), okta.WithOrgUrl(url), okta.WithToken(token))
+// NewClient returns a client for the org at url. opts are applied after the URL and token,
+// e.g. to point the client at a fakeokta server in tests.
+func NewClient(url, token string, opts ...okta.ConfigSetter) (*OktaClient, error) {
+	opts = append([]okta.ConfigSetter{okta.WithOrgUrl(url), okta.WithToken(token)}, opts...)
+	ctx, client, err := okta.NewClient(context.Background(), opts...)
 	if err != nil {
 		return nil, err
 	}
-	return &OktaClient{OktaAppService: client.Application, OktaGroupService: client.Group, Ctx: ctx}, nil
+	return &OktaClient{API: sdkRequester{client}, Ctx: ctx}, nil
 }
 
-func (oc *OktaClient) ListApps(name string) ([]App, error) {
-	qp := query.NewQueryParams(query.WithQ(name), query.WithFilter("status eq \"ACTIVE\""))
-	_, resp, err := oc.OktaAppService.ListApplications(oc.Ctx, qp)
-	if err != nil {
-		return nil, err
+// IsNotFound reports whether err is the API's answer for a resource that does not exist, such
+// as a group deleted while it was still assigned to an app, or a snapshot's.
+func IsNotFound(err error) bool {
+	var e *okta.Error
+	var missing *notInSnapshotError
+	return (errors.As(err, &e) && e.ErrorCode == "E0000007") || errors.As(err, &missing)
+}
+
+// sdkRequester sends requests with the SDK client's RequestExecutor. Each request is built on
+// a copy of the executor, as the SDK's own resources do, since building one resets its headers.
+type sdkRequester struct {
+	client *okta.Client
+}
+
+func (r sdkRequester) NewRequest(method string, url string, body interface{}) (*http.Request, error) {
+	return r.client.CloneRequestExecutor().NewRequest(method, url, body)
+}
+
+func (r sdkRequester) Do(ctx context.Context, req *http.Request, v interface{}) (*okta.Response, error) {
+	return r.client.GetRequestExecutor().Do(ctx, req, v)
+}
+
+// do sends a request without a body to path, with qp if not nil, and decodes the response
+// into v if not nil.
+func (oc *OktaClient) do(ctx context.Context, method, path string, qp *query.Params, v interface{}) (*okta.Response, error) {
+	if qp != nil {
+		path += qp.String()
 	}
-	defer resp.Body.Close()
-	b, err := io.ReadAll(resp.Body)
+	req, err := oc.API.NewRequest(method, path, nil)
 	if err != nil {
 		return nil, err
 	}
-	apps := []App{}
-	if err := json.Unmarshal(b, &apps); err != nil {
-		return nil, err
+	return oc.API.Do(ctx, req, v)
+}
+
+func (oc *OktaClient) get(path string, v interface{}) error {
+	_, err := oc.do(oc.Ctx, http.MethodGet, path, nil, v)
+	return err
+}
+
+// send makes a change that returns nothing the client needs.
+func (oc *OktaClient) send(method, path string, qp *query.Params) error {
+	_, err := oc.do(oc.Ctx, method, path, qp, nil)
+	return err
+}
+
+// list streams the items of the list at path and of every page linked after it. Pages are
+// fetched as the items before them are consumed and decoded once into a []T, so only one page
+// is held in memory at a time. An error is yielded once, with the zero T, and ends the iteration.
+func list[T any](oc *OktaClient, ctx context.Context, path string, qp *query.Params) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		for path != "" {
+			page := []T{}
+			resp, err := oc.do(ctx, http.MethodGet, path, qp, &page)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			for _, item := range page {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			// The next page's link carries the query of the request before it.
+			path, qp = resp.NextPage, nil
+		}
+	}
+}
+
+// collect reads every item of seq, or returns its first error.
+func collect[T any](seq iter.Seq2[T, error]) ([]T, error) {
+	items := []T{}
+	for item, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
 	}
-	return apps, nil
+	return items, nil
+}
+
+func (oc *OktaClient) ListApps(name string) ([]App, error) {
+	return collect(oc.IterApps(oc.Ctx, name))
+}
+
+// IterApps streams the active apps whose name or label starts with name.
+func (oc *OktaClient) IterApps(ctx context.Context, name string) iter.Seq2[App, error] {
+	qp := query.NewQueryParams(query.WithQ(name), query.WithFilter("status eq \"ACTIVE\""))
+	return list[App](oc, ctx, "/api/v1/apps", qp)
 }
 
 func (oc *OktaClient) ListAppsGroups(appID string) (App, []GroupAssignmentResp, error) {
@@ -95,19 +197,10 @@ func (oc *OktaClient) ListAppsGroups(appID string) (App, []GroupAssignmentResp,
 		return app, nil, err
 	}
 	params := query.NewQueryParams(query.WithLimit(200))
-	_, resp, err := oc.OktaAppService.ListApplicationGroupAssignments(oc.Ctx, appID, params)
-	if err != nil {
-		return app, nil, err
-	}
-	defer resp.Body.Close()
-	b, err := io.ReadAll(resp.Body)
+	groups, err := collect(list[GroupAssignmentResp](oc, oc.Ctx, "/api/v1/apps/"+url.PathEscape(appID)+"/groups", params))
 	if err != nil {
 		return app, nil, err
 	}
-	groups := []GroupAssignmentResp{}
-	if err := json.Unmarshal(b, &groups); err != nil {
-		return app, nil, err
-	}
 	for i, group := range groups {
 		g, _ := oc.GetGroupById(group.GroupID)
 		group.Name = g.Name
@@ -116,72 +209,128 @@ func (oc *OktaClient) ListAppsGroups(appID string) (App, []GroupAssignmentResp,
 	return app, groups, nil
 }
 
+// ListAppUsers returns every user assigned to the app with their user profile embedded.
+func (oc *OktaClient) ListAppUsers(appID string) ([]AppUser, error) {
+	return collect(oc.IterAppUsers(oc.Ctx, appID))
+}
+
+// IterAppUsers streams the users assigned to the app, like ListAppUsers.
+func (oc *OktaClient) IterAppUsers(ctx context.Context, appID string) iter.Seq2[AppUser, error] {
+	params := query.NewQueryParams(query.WithLimit(500), query.WithExpand("user"))
+	return list[AppUser](oc, ctx, "/api/v1/apps/"+url.PathEscape(appID)+"/users", params)
+}
+
 func (oc *OktaClient) ListOktaGroups(name string) ([]Group, error) {
-	params := query.NewQueryParams(query.WithLimit(100), query.WithSearch(fmt.Sprintf("profile.name sw \"%s\"", name)))
-	_, resp, err := oc.ListGroups(oc.Ctx, params)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	b, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	groups := []Group{}
-	if err := json.Unmarshal(b, &groups); err != nil {
-		return nil, err
+	return collect(oc.IterGroups(oc.Ctx, name))
+}
+
+// IterGroups streams the groups whose name starts with name, or every group if name is empty.
+func (oc *OktaClient) IterGroups(ctx context.Context, name string) iter.Seq2[Group, error] {
+	params := query.NewQueryParams(query.WithLimit(100))
+	if name != "" {
+		params.Search = "profile.name sw " + quote(name)
 	}
-	return groups, nil
+	return list[Group](oc, ctx, "/api/v1/groups", params)
+}
+
+// quote returns s as a string literal for a filter or search expression, with its backslashes
+// and double quotes escaped so that it cannot end the literal early.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
 }
 
 func (oc *OktaClient) ListOktaGroupUsers(groupID string) ([]User, error) {
+	return collect(oc.IterGroupUsers(oc.Ctx, groupID))
+}
+
+// IterGroupUsers streams the members of the group.
+func (oc *OktaClient) IterGroupUsers(ctx context.Context, groupID string) iter.Seq2[User, error] {
 	params := query.NewQueryParams(query.WithLimit(100))
-	_, resp, err := oc.ListGroupUsers(oc.Ctx, groupID, params)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	b, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	users := []User{}
-	if err := json.Unmarshal(b, &users); err != nil {
-		return nil, err
-	}
-	return users, nil
+	return list[User](oc, ctx, "/api/v1/groups/"+url.PathEscape(groupID)+"/users", params)
 }
 
 func (oc *OktaClient) GetAppById(appID string) (App, error) {
-	_, resp, err := oc.OktaAppService.GetApplication(oc.Ctx, appID, okta.NewApplication(), &query.Params{})
 	app := App{}
-	if err != nil {
-		return app, nil
-	}
-	defer resp.Body.Close()
-	b, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return app, err
-	}
-	if err := json.Unmarshal(b, &app); err != nil {
-		return app, err
+	if err := oc.get("/api/v1/apps/"+url.PathEscape(appID), &app); err != nil {
+		return App{}, err
 	}
 	return app, nil
 }
 
 func (oc *OktaClient) GetGroupById(groupID string) (Group, error) {
 	group := Group{}
-	_, resp, err := oc.OktaGroupService.GetGroup(oc.Ctx, groupID)
-	if err != nil {
-		return group, err
-	}
-	defer resp.Body.Close()
-	b, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return group, err
-	}
-	if err := json.Unmarshal(b, &group); err != nil {
-		return group, err
+	if err := oc.get("/api/v1/groups/"+url.PathEscape(groupID), &group); err != nil {
+		return Group{}, err
 	}
 	return group, nil
 }
+
+// GetUserById returns the user with the given ID or login.
+func (oc *OktaClient) GetUserById(userID string) (User, error) {
+	user := User{}
+	if err := oc.get("/api/v1/users/"+url.PathEscape(userID), &user); err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
+
+// ListOktaUsers returns the users matching search, an Okta search expression such as
+// profile.email eq "alex@example.com".
+func (oc *OktaClient) ListOktaUsers(search string) ([]User, error) {
+	return collect(oc.IterUsers(oc.Ctx, search))
+}
+
+// IterUsers streams the users matching search, like ListOktaUsers. An empty search streams
+// every user that is not deprovisioned.
+func (oc *OktaClient) IterUsers(ctx context.Context, search string) iter.Seq2[User, error] {
+	params := query.NewQueryParams(query.WithLimit(200), query.WithSearch(search))
+	return list[User](oc, ctx, "/api/v1/users", params)
+}
+
+// ListOktaUserGroups returns every group the user is a member of.
+func (oc *OktaClient) ListOktaUserGroups(userID string) ([]Group, error) {
+	return collect(list[Group](oc, oc.Ctx, "/api/v1/users/"+url.PathEscape(userID)+"/groups", nil))
+}
+
+// ListOktaUserApps returns every app the user is assigned to, directly or through a group,
+// whatever the app's status.
+func (oc *OktaClient) ListOktaUserApps(userID string) ([]App, error) {
+	qp := query.NewQueryParams(query.WithLimit(200), query.WithFilter("user.id eq "+quote(userID)))
+	return collect(list[App](oc, oc.Ctx, "/api/v1/apps", qp))
+}
+
+// ListOktaGroupRules returns every group rule in the org.
+func (oc *OktaClient) ListOktaGroupRules() ([]GroupRule, error) {
+	params := query.NewQueryParams(query.WithLimit(200))
+	return collect(list[GroupRule](oc, oc.Ctx, "/api/v1/groups/rules", params))
+}
+
+func (oc *OktaClient) AddOktaGroupUser(groupID, userID string) error {
+	return oc.send(http.MethodPut, "/api/v1/groups/"+url.PathEscape(groupID)+"/users/"+url.PathEscape(userID), nil)
+}
+
+func (oc *OktaClient) RemoveOktaGroupUser(groupID, userID string) error {
+	return oc.send(http.MethodDelete, "/api/v1/groups/"+url.PathEscape(groupID)+"/users/"+url.PathEscape(userID), nil)
+}
+
+// ClearOktaUserSessions ends every session the user has and revokes their OAuth tokens.
+func (oc *OktaClient) ClearOktaUserSessions(userID string) error {
+	return oc.send(http.MethodDelete, "/api/v1/users/"+url.PathEscape(userID)+"/sessions", query.NewQueryParams(query.WithOauthTokens(true)))
+}
+
+func (oc *OktaClient) SuspendOktaUser(userID string) error {
+	return oc.send(http.MethodPost, "/api/v1/users/"+url.PathEscape(userID)+"/lifecycle/suspend", nil)
+}
+
+func (oc *OktaClient) UnsuspendOktaUser(userID string) error {
+	return oc.send(http.MethodPost, "/api/v1/users/"+url.PathEscape(userID)+"/lifecycle/unsuspend", nil)
+}
+
+func (oc *OktaClient) DeactivateOktaUser(userID string) error {
+	return oc.send(http.MethodPost, "/api/v1/users/"+url.PathEscape(userID)+"/lifecycle/deactivate", nil)
+}
+
+// ActivateOktaUser reactivates a deactivated user without sending them an activation email.
+func (oc *OktaClient) ActivateOktaUser(userID string) error {
+	return oc.send(http.MethodPost, "/api/v1/users/"+url.PathEscape(userID)+"/lifecycle/activate", query.NewQueryParams(query.WithSendEmail(false)))
+}
@@ -1,23 +1,96 @@
 package oktaapi
 
 import (
-	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/okta/okta-sdk-golang/v2/okta"
-	"github.com/okta/okta-sdk-golang/v2/okta/query"
 )
 
-type MockOktaAppService struct{}
+// mockRequester answers requests with the canned responses in mockResponses, and records the
+// reads and the changes it is asked to make.
+type mockRequester struct {
+	reads []string
+	calls []string
+}
+
+// mockResponses maps a method and path, with * standing for one segment, to a response body.
+var mockResponses = map[string]string{
+	"GET /api/v1/apps":           appsJSON,
+	"GET /api/v1/apps/*":         appJSON,
+	"GET /api/v1/apps/*/groups":  appGroupsJSON,
+	"GET /api/v1/apps/*/users":   appUsersJSON,
+	"GET /api/v1/groups/*":       groupJSON,
+	"GET /api/v1/groups":         groupsJSON,
+	"GET /api/v1/groups/*/users": groupUsersJSON,
+	"GET /api/v1/groups/rules":   groupRulesJSON,
+	"GET /api/v1/users/*":        userJSON,
+	"GET /api/v1/users":          usersJSON,
+	"GET /api/v1/users/*/groups": userGroupsJSON,
+}
+
+func newMockClient() *OktaClient {
+	return &OktaClient{API: &mockRequester{}, Ctx: context.Background()}
+}
 
-var mockAS OktaAppService = &MockOktaAppService{}
+// assertReads checks that client, made by newMockClient, sent exactly the GET requests in want.
+func assertReads(t *testing.T, client *OktaClient, want ...string) {
+	t.Helper()
+	got := client.API.(*mockRequester).reads
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func (m *mockRequester) NewRequest(method string, url string, body interface{}) (*http.Request, error) {
+	return http.NewRequest(method, "https://example.okta.com"+url, nil)
+}
+
+func (m *mockRequester) Do(ctx context.Context, req *http.Request, v interface{}) (*okta.Response, error) {
+	if req.Method != http.MethodGet {
+		m.calls = append(m.calls, req.Method+" "+req.URL.RequestURI())
+		return &okta.Response{Response: &http.Response{Body: http.NoBody, Status: "204 No Content", StatusCode: 204, Request: req}}, nil
+	}
+	m.reads = append(m.reads, req.Method+" "+req.URL.RequestURI())
+	body, ok := mockResponse(req.Method + " " + req.URL.Path)
+	if !ok {
+		return nil, fmt.Errorf("no mock response for %s %s", req.Method, req.URL.Path)
+	}
+	if err := json.Unmarshal([]byte(body), v); err != nil {
+		return nil, err
+	}
+	return &okta.Response{Response: &http.Response{Body: http.NoBody, Status: "200 OK", StatusCode: 200, Request: req}}, nil
+}
+
+func mockResponse(key string) (string, bool) {
+	if body, ok := mockResponses[key]; ok {
+		return body, true
+	}
+	segs := strings.Split(key, "/")
+	for pattern, body := range mockResponses {
+		p := strings.Split(pattern, "/")
+		if len(p) != len(segs) {
+			continue
+		}
+		match := true
+		for i := range p {
+			if p[i] != "*" && p[i] != segs[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return body, true
+		}
+	}
+	return "", false
+}
 
-func (m *MockOktaAppService) ListApplications(ctx context.Context, qp *query.Params) ([]okta.App, *okta.Response, error) {
-	body := `[
+const appsJSON = `[
 		{
 		  "id": "0oa1gjh63g214q0Hq0g4",
 		  "name": "testorgone_customsaml20app_1",
@@ -209,13 +282,8 @@ func (m *MockOktaAppService) ListApplications(ctx context.Context, qp *query.Par
 		}
 	  ]
 	  `
-	buf := bytes.NewBufferString(body)
-	resp := &http.Response{Body: io.NopCloser(buf), Status: "200 Ok", StatusCode: 200}
-	return []okta.App{}, &okta.Response{Response: resp}, nil
-}
 
-func (m *MockOktaAppService) GetApplication(ctx context.Context, appId string, appInstance okta.App, qp *query.Params) (okta.App, *okta.Response, error) {
-	body := `{
+const appJSON = `{
 	"id": "0oa1gjh63g214q0Hq0g4",
 	"name": "testorgone_customsaml20app_1",
 	"label": "Custom Saml 2.0 App",
@@ -228,13 +296,8 @@ func (m *MockOktaAppService) GetApplication(ctx context.Context, appId string, a
 		"loginRedirectUrl": null
 	}
 }`
-	buf := bytes.NewBufferString(body)
-	resp := &http.Response{Body: io.NopCloser(buf), Status: "200 Ok", StatusCode: 200}
-	return okta.NewApplication(), &okta.Response{Response: resp}, nil
-}
 
-func (m *MockOktaAppService) ListApplicationGroupAssignments(ctx context.Context, appID string, qp *query.Params) ([]*okta.ApplicationGroupAssignment, *okta.Response, error) {
-	body := `[
+const appGroupsJSON = `[
 		{
 		  "id": "00gbkkGFFWZDLCNTAGQR",
 		  "lastUpdated": "2013-10-02T07:38:20.000Z",
@@ -260,17 +323,42 @@ func (m *MockOktaAppService) ListApplicationGroupAssignments(ctx context.Context
 		}
 	  ]
 	  `
-	buf := bytes.NewBufferString(body)
-	resp := &http.Response{Body: io.NopCloser(buf), Status: "200 Ok", StatusCode: 200}
-	return nil, &okta.Response{Response: resp}, nil
-}
-
-type MockOktaGroupService struct{}
 
-var mockGS OktaGroupService = &MockOktaGroupService{}
+const appUsersJSON = `[
+		{
+		  "id": "00u1emaKYZTWRYYRRTSK",
+		  "scope": "USER",
+		  "status": "ACTIVE",
+		  "_embedded": {
+			"user": {
+			  "id": "00u1emaKYZTWRYYRRTSK",
+			  "status": "ACTIVE",
+			  "profile": {
+				"login": "user0@example.com",
+				"email": "user0@example.com"
+			  }
+			}
+		  }
+		},
+		{
+		  "id": "00ugg0xVALADWBPXOFZA",
+		  "scope": "GROUP",
+		  "status": "ACTIVE",
+		  "_embedded": {
+			"user": {
+			  "id": "00ugg0xVALADWBPXOFZA",
+			  "status": "ACTIVE",
+			  "profile": {
+				"login": "user1@example.com",
+				"email": "user1@example.com"
+			  }
+			}
+		  }
+		}
+	  ]
+	  `
 
-func (m *MockOktaGroupService) GetGroup(ctx context.Context, groupId string) (*okta.Group, *okta.Response, error) {
-	body := `{
+const groupJSON = `{
 		"id": "00g1emaKYZTWRYYRRTSK",
 		"created": "2015-02-06T10:11:28.000Z",
 		"lastUpdated": "2015-10-05T19:16:43.000Z",
@@ -305,13 +393,8 @@ func (m *MockOktaGroupService) GetGroup(ctx context.Context, groupId string) (*o
 		}
 	  }
 	  `
-	buf := bytes.NewBufferString(body)
-	resp := &http.Response{Body: io.NopCloser(buf), Status: "200 Ok", StatusCode: 200}
-	return nil, &okta.Response{Response: resp}, nil
-}
 
-func (m *MockOktaGroupService) ListGroups(ctx context.Context, qp *query.Params) ([]*okta.Group, *okta.Response, error) {
-	body := `[
+const groupsJSON = `[
 		{
 		  "id": "00g1emaKYZTWRYYRRTSK",
 		  "created": "2015-02-06T10:11:28.000Z",
@@ -382,12 +465,7 @@ func (m *MockOktaGroupService) ListGroups(ctx context.Context, qp *query.Params)
 		}
 	  ]`
 
-	buf := bytes.NewBufferString(body)
-	return nil, &okta.Response{Response: &http.Response{Body: io.NopCloser(buf), Status: "200 OK", StatusCode: 200}}, nil
-}
-
-func (m *MockOktaGroupService) ListGroupUsers(ctx context.Context, groupId string, qp *query.Params) ([]*okta.User, *okta.Response, error) {
-	body := `[
+const groupUsersJSON = `[
 		{
 		  "id": "00g1emaKYZTWRYYRRTSK",
 		  "status": "ACTIVE",
@@ -405,12 +483,84 @@ func (m *MockOktaGroupService) ListGroupUsers(ctx context.Context, groupId strin
 	}
 	]
 	`
-	buf := bytes.NewBufferString(body)
-	resp := &http.Response{Body: io.NopCloser(buf), Status: "200 Ok", StatusCode: 200}
-	return nil, &okta.Response{Response: resp}, nil
-}
+
+const groupRulesJSON = `[
+		{
+		  "type": "group_rule",
+		  "id": "0pr3f7zMZZHPgUoWO0g4",
+		  "status": "ACTIVE",
+		  "name": "Engineering group rule",
+		  "conditions": {
+			"expression": {
+			  "value": "user.role==\"Engineer\"",
+			  "type": "urn:okta:expression:1.0"
+			}
+		  },
+		  "actions": {
+			"assignUserToGroups": {
+			  "groupIds": [
+				"00gjitX9HqABSoqTB0g3"
+			  ]
+			}
+		  }
+		}
+	  ]
+	  `
+
+const userJSON = `{
+		"id": "00ub0oNGTSWTBKOLGLNR",
+		"status": "ACTIVE",
+		"created": "2013-06-24T16:39:18.000Z",
+		"activated": "2013-06-24T16:39:19.000Z",
+		"statusChanged": "2013-06-24T16:39:19.000Z",
+		"lastLogin": "2013-06-24T17:39:19.000Z",
+		"lastUpdated": "2013-07-02T21:36:25.344Z",
+		"passwordChanged": "2013-07-02T21:36:25.344Z",
+		"profile": {
+		  "firstName": "Isaac",
+		  "lastName": "Brock",
+		  "email": "isaac.brock@example.com",
+		  "login": "isaac.brock@example.com",
+		  "mobilePhone": "555-415-1337"
+		}
+	  }
+	  `
+
+const usersJSON = `[
+		{
+		  "id": "00ub0oNGTSWTBKOLGLNR",
+		  "status": "ACTIVE",
+		  "profile": {
+		    "firstName": "Isaac",
+		    "lastName": "Brock",
+		    "email": "isaac.brock@example.com",
+		    "login": "isaac.brock@example.com"
+		  }
+		}
+	  ]`
+
+const userGroupsJSON = `[
+		{
+		  "id": "0gabcd1234",
+		  "type": "OKTA_GROUP",
+		  "profile": {
+			"name": "Cloud App Users",
+			"description": "Users can access cloud apps"
+		  }
+		},
+		{
+		  "id": "0gefgh5678",
+		  "type": "BUILT_IN",
+		  "profile": {
+			"name": "Everyone",
+			"description": "All users in your organization"
+		  }
+		}
+	  ]
+	  `
+
 func TestOktaClient_ListApps(t *testing.T) {
-	client := &OktaClient{OktaAppService: mockAS, OktaGroupService: mockGS, Ctx: context.Background()}
+	client := newMockClient()
 	apps, err := client.ListApps("datadog")
 	if err != nil {
 		t.Error(err)
@@ -421,7 +571,7 @@ func TestOktaClient_ListApps(t *testing.T) {
 }
 
 func TestOktaClient_GetAppById(t *testing.T) {
-	client := &OktaClient{OktaAppService: mockAS, OktaGroupService: mockGS, Ctx: context.Background()}
+	client := newMockClient()
 	app, err := client.GetAppById("0oa1gjh63g214q0Hq0g4")
 	if err != nil {
 		t.Error(err)
@@ -430,7 +580,7 @@ func TestOktaClient_GetAppById(t *testing.T) {
 }
 
 func TestOktaClient_ListAppsGroups(t *testing.T) {
-	client := &OktaClient{OktaAppService: mockAS, OktaGroupService: mockGS, Ctx: context.Background()}
+	client := newMockClient()
 	app, groups, err := client.ListAppsGroups("0oa1gjh63g214q0Hq0g4")
 	if err != nil {
 		t.Error(err)
@@ -449,7 +599,7 @@ func TestOktaClient_ListAppsGroups(t *testing.T) {
 }
 
 func TestOktaClient_ListGroups(t *testing.T) {
-	client := &OktaClient{OktaAppService: mockAS, OktaGroupService: mockGS, Ctx: context.Background()}
+	client := newMockClient()
 	groups, err := client.ListOktaGroups("test")
 	if err != nil {
 		t.Error(err)
@@ -460,7 +610,7 @@ func TestOktaClient_ListGroups(t *testing.T) {
 }
 
 func TestOktaClient_ListGroupUsers(t *testing.T) {
-	client := &OktaClient{OktaAppService: mockAS, OktaGroupService: mockGS, Ctx: context.Background()}
+	client := newMockClient()
 	users, err := client.ListOktaGroupUsers("0oa1gjh63g214q0Hq0g4")
 	if err != nil {
 		t.Error(err)
@@ -469,3 +619,165 @@ func TestOktaClient_ListGroupUsers(t *testing.T) {
 		fmt.Printf("%s  %s\n", u.ID, u.Profile.Email)
 	}
 }
+
+func TestOktaClient_ListAppUsers(t *testing.T) {
+	client := newMockClient()
+	users, err := client.ListAppUsers("0oa1gjh63g214q0Hq0g4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertReads(t, client, "GET /api/v1/apps/0oa1gjh63g214q0Hq0g4/users?expand=user&limit=500")
+	if len(users) != 2 {
+		t.Fatalf("got %d users, want 2", len(users))
+	}
+	if u := users[0]; u.ID != "00u1emaKYZTWRYYRRTSK" || u.Scope != "USER" || u.Embedded.User.Email != "user0@example.com" {
+		t.Errorf("users[0] = %+v, want a direct assignment of user0@example.com", u)
+	}
+	if u := users[1]; u.Scope != "GROUP" || u.Embedded.User.Login != "user1@example.com" {
+		t.Errorf("users[1] = %+v, want a group assignment of user1@example.com", u)
+	}
+}
+
+func TestOktaClient_GetUserById(t *testing.T) {
+	client := newMockClient()
+	user, err := client.GetUserById("isaac.brock@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertReads(t, client, "GET /api/v1/users/isaac.brock@example.com")
+	if user.ID != "00ub0oNGTSWTBKOLGLNR" || user.Login != "isaac.brock@example.com" || user.LastLogin != "2013-06-24T17:39:19.000Z" {
+		t.Errorf("user = %+v, want Isaac Brock", user)
+	}
+}
+
+func TestOktaClient_ListOktaUsers(t *testing.T) {
+	client := newMockClient()
+	users, err := client.ListOktaUsers(`profile.email eq "isaac.brock@example.com"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertReads(t, client, "GET /api/v1/users?limit=200&search=profile.email+eq+%22isaac.brock%40example.com%22")
+	if len(users) != 1 || users[0].ID != "00ub0oNGTSWTBKOLGLNR" || users[0].Email != "isaac.brock@example.com" {
+		t.Errorf("users = %+v, want Isaac Brock", users)
+	}
+}
+
+func TestOktaClient_ListOktaGroupRules(t *testing.T) {
+	client := newMockClient()
+	rules, err := client.ListOktaGroupRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertReads(t, client, "GET /api/v1/groups/rules?limit=200")
+	if len(rules) != 1 {
+		t.Fatalf("got %d rules, want 1", len(rules))
+	}
+	r := rules[0]
+	if r.ID != "0pr3f7zMZZHPgUoWO0g4" || r.Name != "Engineering group rule" || r.Status != "ACTIVE" {
+		t.Errorf("rule = %+v, want the Engineering group rule", r)
+	}
+	if groups := r.Actions.AssignUserToGroups.GroupIDs; len(groups) != 1 || groups[0] != "00gjitX9HqABSoqTB0g3" {
+		t.Errorf("rule assigns %v, want [00gjitX9HqABSoqTB0g3]", groups)
+	}
+}
+
+func TestOktaClient_ListOktaUserGroups(t *testing.T) {
+	client := newMockClient()
+	groups, err := client.ListOktaUserGroups("00ub0oNGTSWTBKOLGLNR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertReads(t, client, "GET /api/v1/users/00ub0oNGTSWTBKOLGLNR/groups")
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2", len(groups))
+	}
+	if g := groups[0]; g.ID != "0gabcd1234" || g.Type != "OKTA_GROUP" || g.Name != "Cloud App Users" {
+		t.Errorf("groups[0] = %+v, want Cloud App Users", g)
+	}
+	if g := groups[1]; g.ID != "0gefgh5678" || g.Type != "BUILT_IN" || g.Name != "Everyone" {
+		t.Errorf("groups[1] = %+v, want Everyone", g)
+	}
+}
+
+func TestOktaClient_ListOktaUserApps(t *testing.T) {
+	client := newMockClient()
+	apps, err := client.ListOktaUserApps("00ub0oNGTSWTBKOLGLNR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertReads(t, client, "GET /api/v1/apps?filter=user.id+eq+%2200ub0oNGTSWTBKOLGLNR%22&limit=200")
+	if len(apps) != 2 {
+		t.Fatalf("got %d apps, want 2", len(apps))
+	}
+	if apps[0].ID != "0oa1gjh63g214q0Hq0g4" || apps[0].Label != "Custom Saml 2.0 App" {
+		t.Errorf("apps[0] = %+v, want Custom Saml 2.0 App", apps[0])
+	}
+	if apps[1].ID != "0oabkvBLDEKCNXBGYUAS" || apps[1].Label != "Sample Plugin App" {
+		t.Errorf("apps[1] = %+v, want Sample Plugin App", apps[1])
+	}
+}
+
+func TestOktaClient_Writes(t *testing.T) {
+	api := &mockRequester{}
+	client := &OktaClient{API: api, Ctx: context.Background()}
+	const user = "00ub0oNGTSWTBKOLGLNR"
+	for _, err := range []error{
+		client.AddOktaGroupUser("00g1emaKYZTWRYYRRTSK", user),
+		client.RemoveOktaGroupUser("00g1emaKYZTWRYYRRTSK", user),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{
+		"PUT /api/v1/groups/00g1emaKYZTWRYYRRTSK/users/" + user,
+		"DELETE /api/v1/groups/00g1emaKYZTWRYYRRTSK/users/" + user,
+	}
+	if strings.Join(api.calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests:\n%s\nwant:\n%s", strings.Join(api.calls, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestOktaClient_UserLifecycle(t *testing.T) {
+	api := &mockRequester{}
+	client := &OktaClient{API: api, Ctx: context.Background()}
+	const user = "00ub0oNGTSWTBKOLGLNR"
+	for _, err := range []error{
+		client.ClearOktaUserSessions(user),
+		client.SuspendOktaUser(user),
+		client.UnsuspendOktaUser(user),
+		client.DeactivateOktaUser(user),
+		client.ActivateOktaUser(user),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{
+		"DELETE /api/v1/users/" + user + "/sessions?oauthTokens=true",
+		"POST /api/v1/users/" + user + "/lifecycle/suspend",
+		"POST /api/v1/users/" + user + "/lifecycle/unsuspend",
+		"POST /api/v1/users/" + user + "/lifecycle/deactivate",
+		"POST /api/v1/users/" + user + "/lifecycle/activate?sendEmail=false",
+	}
+	if strings.Join(api.calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests:\n%s\nwant:\n%s", strings.Join(api.calls, "\n"), strings.Join(want, "\n"))
+	}
+	if len(api.reads) != 0 {
+		t.Errorf("lifecycle changes read %v, want no reads", api.reads)
+	}
+}
+
+func TestOktaClient_QuotesSearchValues(t *testing.T) {
+	client := newMockClient()
+	if _, err := client.ListOktaGroups(`R&D "core" \ infra`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ListOktaUserApps(`00u1" or user.id eq "00u2`); err != nil {
+		t.Fatal(err)
+	}
+	assertReads(t, client,
+		`GET /api/v1/groups?limit=100&search=profile.name+sw+%22R%26D+%5C%22core%5C%22+%5C%5C+infra%22`,
+		`GET /api/v1/apps?filter=user.id+eq+%2200u1%5C%22+or+user.id+eq+%5C%2200u2%22&limit=200`,
+	)
+}
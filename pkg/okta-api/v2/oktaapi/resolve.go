@@ -0,0 +1,148 @@
+package oktaapi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxCandidates is how many matches an AmbiguousError lists.
+const maxCandidates = 10
+
+// GroupFinder searches groups by name prefix. OktaClient and Snapshot implement it.
+type GroupFinder interface {
+	ListOktaGroups(name string) ([]Group, error)
+}
+
+// AppFinder searches apps by name or label prefix. OktaClient and Snapshot implement it.
+type AppFinder interface {
+	ListApps(name string) ([]App, error)
+}
+
+// UserFinder finds users by ID or login, or with a search expression.
+type UserFinder interface {
+	GetUserById(userID string) (User, error)
+	ListOktaUsers(search string) ([]User, error)
+}
+
+// Candidate is one of the resources an ambiguous reference matches.
+type Candidate struct {
+	ID   string
+	Name string
+}
+
+// AmbiguousError is returned when a name matches more than one resource.
+type AmbiguousError struct {
+	Kind       string
+	Ref        string
+	Candidates []Candidate
+}
+
+func (e *AmbiguousError) Error() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%s %q is ambiguous, it matches %d %ss:", e.Kind, e.Ref, len(e.Candidates), e.Kind)
+	for i, c := range e.Candidates {
+		if i == maxCandidates {
+			fmt.Fprintf(b, "\n  and %d more", len(e.Candidates)-maxCandidates)
+			break
+		}
+		fmt.Fprintf(b, "\n  %s  %s", c.ID, c.Name)
+	}
+	b.WriteString("\nuse the ID or a longer name")
+	return b.String()
+}
+
+// ResolveGroupID returns the ID of the group ref names. ref is a group ID, a group name, or a
+// prefix of exactly one group's name. Names are matched ignoring case.
+func ResolveGroupID(f GroupFinder, ref string) (string, error) {
+	if looksLikeID(ref, "00g") {
+		return ref, nil
+	}
+	groups, err := f.ListOktaGroups(ref)
+	if err != nil {
+		return "", err
+	}
+	candidates := make([]Candidate, 0, len(groups))
+	for _, g := range groups {
+		candidates = append(candidates, Candidate{ID: g.ID, Name: g.Name})
+	}
+	return pick("group", ref, candidates, func(c Candidate) bool { return strings.EqualFold(c.Name, ref) })
+}
+
+// ResolveAppID returns the ID of the app ref names. ref is an app ID, an app label or name,
+// or a prefix of exactly one app's label or name. Labels are matched ignoring case.
+func ResolveAppID(f AppFinder, ref string) (string, error) {
+	if looksLikeID(ref, "0oa") {
+		return ref, nil
+	}
+	apps, err := f.ListApps(ref)
+	if err != nil {
+		return "", err
+	}
+	candidates := make([]Candidate, 0, len(apps))
+	names := map[string]string{}
+	for _, app := range apps {
+		candidates = append(candidates, Candidate{ID: app.ID, Name: app.Label})
+		names[app.ID] = app.Name
+	}
+	return pick("app", ref, candidates, func(c Candidate) bool {
+		return strings.EqualFold(c.Name, ref) || strings.EqualFold(names[c.ID], ref)
+	})
+}
+
+// ResolveUser returns the user ref names. ref is a user ID, a login, or an email address that
+// belongs to exactly one user.
+func ResolveUser(f UserFinder, ref string) (User, error) {
+	user, err := f.GetUserById(ref)
+	if err == nil || !strings.Contains(ref, "@") {
+		return user, err
+	}
+	users, serr := f.ListOktaUsers(fmt.Sprintf("profile.email eq %q", ref))
+	if serr != nil || len(users) == 0 {
+		return user, err
+	}
+	if len(users) > 1 {
+		candidates := make([]Candidate, 0, len(users))
+		for _, u := range users {
+			candidates = append(candidates, Candidate{ID: u.ID, Name: u.Login})
+		}
+		return User{}, &AmbiguousError{Kind: "user", Ref: ref, Candidates: candidates}
+	}
+	return users[0], nil
+}
+
+// pick returns the only candidate, or the only exact match when a prefix matched several.
+func pick(kind, ref string, candidates []Candidate, exact func(Candidate) bool) (string, error) {
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no %s matches %q", kind, ref)
+	}
+	if len(candidates) == 1 {
+		return candidates[0].ID, nil
+	}
+	exacts := []Candidate{}
+	for _, c := range candidates {
+		if exact(c) {
+			exacts = append(exacts, c)
+		}
+	}
+	if len(exacts) == 1 {
+		return exacts[0].ID, nil
+	}
+	if len(exacts) > 1 {
+		candidates = exacts
+	}
+	return "", &AmbiguousError{Kind: kind, Ref: ref, Candidates: candidates}
+}
+
+// looksLikeID reports whether ref has the shape of an Okta ID with the given prefix: 20
+// letters and digits.
+func looksLikeID(ref, prefix string) bool {
+	if len(ref) != 20 || !strings.HasPrefix(ref, prefix) {
+		return false
+	}
+	for _, r := range ref {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,126 @@
+package oktaapi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func resolveSnapshot() *Snapshot {
+	return &Snapshot{
+		Apps: []App{
+			{ID: "0oa1aws0000000000001", Name: "amazon_aws", Label: "AWS Prod"},
+			{ID: "0oa1aws0000000000002", Name: "amazon_aws", Label: "AWS Prod Readonly"},
+			{ID: "0oa1wiki000000000003", Name: "confluence", Label: "Wiki"},
+		},
+		Groups: []Group{
+			{ID: "00g1eng0000000000001", Profile: Profile{Name: "Engineering"}},
+			{ID: "00g1eng0000000000002", Profile: Profile{Name: "Engineering Managers"}},
+			{ID: "00g1ops0000000000003", Profile: Profile{Name: "Ops"}},
+		},
+	}
+}
+
+func TestResolveGroupID(t *testing.T) {
+	snap := resolveSnapshot()
+	for ref, want := range map[string]string{
+		"00g1ops0000000000003": "00g1ops0000000000003",
+		"engineering":          "00g1eng0000000000001",
+		"Engineering M":        "00g1eng0000000000002",
+		"op":                   "00g1ops0000000000003",
+	} {
+		got, err := ResolveGroupID(snap, ref)
+		if err != nil || got != want {
+			t.Errorf("ResolveGroupID(%q) = %s, %v, want %s", ref, got, err, want)
+		}
+	}
+	_, err := ResolveGroupID(snap, "eng")
+	var ambiguous *AmbiguousError
+	if !errors.As(err, &ambiguous) || len(ambiguous.Candidates) != 2 {
+		t.Fatalf("ResolveGroupID(eng) error = %v, want ambiguous with 2 candidates", err)
+	}
+	for _, want := range []string{`group "eng" is ambiguous`, "00g1eng0000000000001  Engineering\n", "00g1eng0000000000002  Engineering Managers"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+	if _, err := ResolveGroupID(snap, "sales"); err == nil || err.Error() != `no group matches "sales"` {
+		t.Errorf("ResolveGroupID(sales) error = %v", err)
+	}
+}
+
+func TestResolveAppID(t *testing.T) {
+	snap := resolveSnapshot()
+	for ref, want := range map[string]string{
+		"0oa1wiki000000000003": "0oa1wiki000000000003",
+		"aws prod":             "0oa1aws0000000000001",
+		"AWS Prod R":           "0oa1aws0000000000002",
+		"confluence":           "0oa1wiki000000000003",
+	} {
+		got, err := ResolveAppID(snap, ref)
+		if err != nil || got != want {
+			t.Errorf("ResolveAppID(%q) = %s, %v, want %s", ref, got, err, want)
+		}
+	}
+	var ambiguous *AmbiguousError
+	if _, err := ResolveAppID(snap, "amazon_aws"); !errors.As(err, &ambiguous) || len(ambiguous.Candidates) != 2 {
+		t.Errorf("ResolveAppID(amazon_aws) error = %v, want ambiguous with 2 candidates", err)
+	}
+}
+
+type stubUserFinder []User
+
+func (s stubUserFinder) GetUserById(ref string) (User, error) {
+	for _, u := range s {
+		if u.ID == ref || u.Login == ref {
+			return u, nil
+		}
+	}
+	return User{}, fmt.Errorf("user %s not found", ref)
+}
+
+func (s stubUserFinder) ListOktaUsers(search string) ([]User, error) {
+	users := []User{}
+	for _, u := range s {
+		if search == fmt.Sprintf("profile.email eq %q", u.Email) {
+			users = append(users, u)
+		}
+	}
+	return users, nil
+}
+
+func TestResolveUser(t *testing.T) {
+	users := stubUserFinder{
+		{ID: "00u1alex000000000001", Profile: Profile{Login: "alex", Email: "alex@example.com"}},
+		{ID: "00u1bobb000000000002", Profile: Profile{Login: "bob", Email: "shared@example.com"}},
+		{ID: "00u1cara000000000003", Profile: Profile{Login: "cara", Email: "shared@example.com"}},
+	}
+	for ref, want := range map[string]string{
+		"00u1alex000000000001": "00u1alex000000000001",
+		"alex":                 "00u1alex000000000001",
+		"alex@example.com":     "00u1alex000000000001",
+	} {
+		got, err := ResolveUser(users, ref)
+		if err != nil || got.ID != want {
+			t.Errorf("ResolveUser(%q) = %s, %v, want %s", ref, got.ID, err, want)
+		}
+	}
+	var ambiguous *AmbiguousError
+	if _, err := ResolveUser(users, "shared@example.com"); !errors.As(err, &ambiguous) || len(ambiguous.Candidates) != 2 {
+		t.Errorf("ResolveUser(shared@example.com) error = %v, want ambiguous with 2 candidates", err)
+	}
+	if _, err := ResolveUser(users, "nobody@example.com"); err == nil || err.Error() != "user nobody@example.com not found" {
+		t.Errorf("ResolveUser(nobody@example.com) error = %v", err)
+	}
+}
+
+func TestAmbiguousErrorTruncates(t *testing.T) {
+	err := &AmbiguousError{Kind: "group", Ref: "g"}
+	for i := 0; i < 12; i++ {
+		err.Candidates = append(err.Candidates, Candidate{ID: fmt.Sprintf("00g%017d", i), Name: fmt.Sprintf("g%d", i)})
+	}
+	if !strings.Contains(err.Error(), "\n  and 2 more\n") {
+		t.Errorf("error = %s", err)
+	}
+}
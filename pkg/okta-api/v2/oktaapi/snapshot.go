@@ -0,0 +1,206 @@
+package oktaapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	snapshotAppsFile       = "apps.json"
+	snapshotAppGroupsFile  = "app_groups.json"
+	snapshotAppUsersFile   = "app_users.json"
+	snapshotGroupsFile     = "groups.json"
+	snapshotGroupUsersFile = "group_users.json"
+)
+
+// Snapshot is an offline copy of the org data used by the list commands.
+// It answers the same queries as OktaClient without any network access.
+type Snapshot struct {
+	Apps       []App
+	AppGroups  map[string][]GroupAssignmentResp
+	AppUsers   map[string][]AppUser
+	Groups     []Group
+	GroupUsers map[string][]User
+}
+
+func newSnapshot() *Snapshot {
+	return &Snapshot{AppGroups: map[string][]GroupAssignmentResp{}, AppUsers: map[string][]AppUser{}, GroupUsers: map[string][]User{}}
+}
+
+// Snapshot exports every active app, group, app assignment and group member in the org.
+func (oc *OktaClient) Snapshot() (*Snapshot, error) {
+	snap := newSnapshot()
+	apps, err := oc.ListApps("")
+	if err != nil {
+		return nil, err
+	}
+	snap.Apps = apps
+	for _, app := range apps {
+		_, groups, err := oc.ListAppsGroups(app.ID)
+		if err != nil {
+			return nil, err
+		}
+		snap.AppGroups[app.ID] = groups
+		users, err := oc.ListAppUsers(app.ID)
+		if err != nil {
+			return nil, err
+		}
+		snap.AppUsers[app.ID] = users
+	}
+	groups, err := oc.ListOktaGroups("")
+	if err != nil {
+		return nil, err
+	}
+	snap.Groups = groups
+	for _, group := range groups {
+		users, err := oc.ListOktaGroupUsers(group.ID)
+		if err != nil {
+			return nil, err
+		}
+		snap.GroupUsers[group.ID] = users
+	}
+	return snap, nil
+}
+
+// LoadSnapshot reads a snapshot previously written with Save from dir.
+func LoadSnapshot(dir string) (*Snapshot, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("snapshot %s is not a directory", dir)
+	}
+	snap := newSnapshot()
+	files := map[string]interface{}{
+		snapshotAppsFile:       &snap.Apps,
+		snapshotAppGroupsFile:  &snap.AppGroups,
+		snapshotAppUsersFile:   &snap.AppUsers,
+		snapshotGroupsFile:     &snap.Groups,
+		snapshotGroupUsersFile: &snap.GroupUsers,
+	}
+	for name, v := range files {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, v); err != nil {
+			return nil, fmt.Errorf("reading snapshot %s: %w", name, err)
+		}
+	}
+	return snap, nil
+}
+
+// Save writes the snapshot to dir as one JSON file per resource, creating dir if needed.
+func (s *Snapshot) Save(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	files := map[string]interface{}{
+		snapshotAppsFile:       s.Apps,
+		snapshotAppGroupsFile:  s.AppGroups,
+		snapshotAppUsersFile:   s.AppUsers,
+		snapshotGroupsFile:     s.Groups,
+		snapshotGroupUsersFile: s.GroupUsers,
+	}
+	for name, v := range files {
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), b, 0o600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListApps returns the apps whose name or label starts with name, ignoring case.
+func (s *Snapshot) ListApps(name string) ([]App, error) {
+	apps := []App{}
+	for _, app := range s.Apps {
+		if hasPrefixFold(app.Name, name) || hasPrefixFold(app.Label, name) {
+			apps = append(apps, app)
+		}
+	}
+	return apps, nil
+}
+
+func (s *Snapshot) GetAppById(appID string) (App, error) {
+	for _, app := range s.Apps {
+		if app.ID == appID {
+			return app, nil
+		}
+	}
+	return App{}, &notInSnapshotError{Kind: "app", ID: appID}
+}
+
+func (s *Snapshot) ListAppsGroups(appID string) (App, []GroupAssignmentResp, error) {
+	app, err := s.GetAppById(appID)
+	if err != nil {
+		return app, nil, err
+	}
+	return app, s.AppGroups[appID], nil
+}
+
+func (s *Snapshot) ListAppUsers(appID string) ([]AppUser, error) {
+	if _, err := s.GetAppById(appID); err != nil {
+		return nil, err
+	}
+	return s.AppUsers[appID], nil
+}
+
+// ListOktaGroups returns the groups whose name starts with name, ignoring case.
+func (s *Snapshot) ListOktaGroups(name string) ([]Group, error) {
+	groups := []Group{}
+	for _, group := range s.Groups {
+		if hasPrefixFold(group.Name, name) {
+			groups = append(groups, group)
+		}
+	}
+	return groups, nil
+}
+
+func (s *Snapshot) ListOktaGroupUsers(groupID string) ([]User, error) {
+	users, ok := s.GroupUsers[groupID]
+	if !ok {
+		return nil, &notInSnapshotError{Kind: "group", ID: groupID}
+	}
+	return users, nil
+}
+
+// ListOktaUserGroups returns the groups in the snapshot that userID is a member of.
+func (s *Snapshot) ListOktaUserGroups(userID string) ([]Group, error) {
+	groups := []Group{}
+	for _, group := range s.Groups {
+		for _, user := range s.GroupUsers[group.ID] {
+			if user.ID == userID {
+				groups = append(groups, group)
+				break
+			}
+		}
+	}
+	return groups, nil
+}
+
+// notInSnapshotError is returned for a resource the snapshot does not have, as the API answers
+// not found for one that does not exist. IsNotFound reports true for it.
+type notInSnapshotError struct {
+	Kind, ID string
+}
+
+func (e *notInSnapshotError) Error() string {
+	return fmt.Sprintf("%s %s not found in snapshot", e.Kind, e.ID)
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
@@ -0,0 +1,67 @@
+package oktaapi
+
+import (
+	"testing"
+)
+
+func TestSnapshot_SaveLoad(t *testing.T) {
+	client := newMockClient()
+	snap, err := client.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := snap.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadSnapshot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apps, err := loaded.ListApps("custom")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(apps) != 1 || apps[0].ID != "0oa1gjh63g214q0Hq0g4" {
+		t.Errorf("ListApps(custom) = %v, want app 0oa1gjh63g214q0Hq0g4", apps)
+	}
+	app, groups, err := loaded.ListAppsGroups("0oa1gjh63g214q0Hq0g4")
+	if err != nil {
+		t.Error(err)
+	}
+	if app.Label != "Custom Saml 2.0 App" || len(groups) != 3 {
+		t.Errorf("ListAppsGroups = %s with %d groups, want Custom Saml 2.0 App with 3 groups", app.Label, len(groups))
+	}
+	groupList, err := loaded.ListOktaGroups("west")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(groupList) != 1 {
+		t.Errorf("ListOktaGroups(west) returned %d groups, want 1", len(groupList))
+	}
+	users, err := loaded.ListOktaGroupUsers("00g1emaKYZTWRYYRRTSK")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(users) != 1 || users[0].Email != "user0@example.com" {
+		t.Errorf("ListOktaGroupUsers = %v, want user0@example.com", users)
+	}
+	userGroups, err := loaded.ListOktaUserGroups(users[0].ID)
+	if err != nil {
+		t.Error(err)
+	}
+	// The mock group service returns the same members for every group.
+	if len(userGroups) != len(loaded.Groups) || userGroups[0].ID != "00g1emaKYZTWRYYRRTSK" {
+		t.Errorf("ListOktaUserGroups = %v, want every group", userGroups)
+	}
+}
+
+func TestSnapshot_NotFound(t *testing.T) {
+	snap := &Snapshot{}
+	if _, err := snap.GetAppById("0oa1gjh63g214q0Hq0g4"); !IsNotFound(err) {
+		t.Errorf("app missing from snapshot error = %v, want not found", err)
+	}
+	if _, err := snap.ListOktaGroupUsers("00g1emaKYZTWRYYRRTSK"); !IsNotFound(err) {
+		t.Errorf("group missing from snapshot error = %v, want not found", err)
+	}
+}
@@ -0,0 +1,115 @@
+// Package watch polls the members of an Okta resource and reports who was added or removed.
+package watch
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Actions reported in an Event.
+const (
+	Added   = "added"
+	Removed = "removed"
+)
+
+// Resources that can be watched.
+const (
+	Group = "group"
+	App   = "app"
+)
+
+// Members maps member IDs to display names.
+type Members map[string]string
+
+// Event is a single membership change seen between two polls.
+type Event struct {
+	Time       time.Time `json:"time"`
+	Resource   string    `json:"resource"`
+	ResourceID string    `json:"resourceId"`
+	Action     string    `json:"action"`
+	MemberID   string    `json:"memberId"`
+	MemberName string    `json:"memberName,omitempty"`
+}
+
+// Diff returns the members of cur not in prev and the members of prev not in cur, sorted by ID.
+func Diff(prev, cur Members) (added, removed []string) {
+	for id := range cur {
+		if _, ok := prev[id]; !ok {
+			added = append(added, id)
+		}
+	}
+	for id := range prev {
+		if _, ok := cur[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// Events describes the change from prev to cur as events at t, additions first.
+func Events(resource, id string, prev, cur Members, t time.Time) []Event {
+	added, removed := Diff(prev, cur)
+	events := []Event{}
+	for _, m := range added {
+		events = append(events, Event{Time: t, Resource: resource, ResourceID: id, Action: Added, MemberID: m, MemberName: cur[m]})
+	}
+	for _, m := range removed {
+		events = append(events, Event{Time: t, Resource: resource, ResourceID: id, Action: Removed, MemberID: m, MemberName: prev[m]})
+	}
+	return events
+}
+
+// Watcher polls Fetch every Interval and emits an event for every member added or removed since the last poll.
+type Watcher struct {
+	Resource string
+	ID       string
+	Interval time.Duration
+	Fetch    func() (Members, error)
+	// OnError is called when a poll after the first fails. The watcher keeps the last good state
+	// and tries again at the next interval. If OnError is nil, Run returns the error instead.
+	OnError func(error)
+	// Now returns the time events are stamped with. It defaults to time.Now.
+	Now func() time.Time
+}
+
+// Run fetches the initial members, which produce no events, then polls until ctx is done
+// or emit returns an error. If started is not nil it is called with the initial members.
+func (w *Watcher) Run(ctx context.Context, started func(Members), emit func(Event) error) error {
+	now := w.Now
+	if now == nil {
+		now = time.Now
+	}
+	prev, err := w.Fetch()
+	if err != nil {
+		return err
+	}
+	if started != nil {
+		started(prev)
+	}
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		cur, err := w.Fetch()
+		if err != nil {
+			if w.OnError == nil {
+				return err
+			}
+			w.OnError(err)
+			continue
+		}
+		for _, e := range Events(w.Resource, w.ID, prev, cur, now()) {
+			if err := emit(e); err != nil {
+				return err
+			}
+		}
+		prev = cur
+	}
+}
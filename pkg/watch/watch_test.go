@@ -0,0 +1,78 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	prev := Members{"00u1": "a", "00u2": "b"}
+	cur := Members{"00u2": "b", "00u4": "d", "00u3": "c"}
+	added, removed := Diff(prev, cur)
+	if !reflect.DeepEqual(added, []string{"00u3", "00u4"}) {
+		t.Errorf("added = %v", added)
+	}
+	if !reflect.DeepEqual(removed, []string{"00u1"}) {
+		t.Errorf("removed = %v", removed)
+	}
+	if added, removed := Diff(cur, cur); len(added) != 0 || len(removed) != 0 {
+		t.Errorf("Diff of identical members = %v, %v", added, removed)
+	}
+}
+
+func TestWatcherRun(t *testing.T) {
+	polls := []Members{
+		{"00u1": "a"},
+		{"00u1": "a", "00u2": "b"},
+		nil,
+		{"00u2": "b"},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	var errs []error
+	w := &Watcher{
+		Resource: "group",
+		ID:       "00g1",
+		Interval: time.Millisecond,
+		Fetch: func() (Members, error) {
+			if len(polls) == 0 {
+				cancel()
+				return Members{"00u2": "b"}, nil
+			}
+			m := polls[0]
+			polls = polls[1:]
+			if m == nil {
+				return nil, errors.New("rate limited")
+			}
+			return m, nil
+		},
+		OnError: func(err error) { errs = append(errs, err) },
+		Now:     func() time.Time { return at },
+	}
+	var initial Members
+	var events []Event
+	err := w.Run(ctx, func(m Members) { initial = m }, func(e Event) error {
+		events = append(events, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(initial) != 1 {
+		t.Errorf("initial members = %v", initial)
+	}
+	want := []Event{
+		{Time: at, Resource: "group", ResourceID: "00g1", Action: Added, MemberID: "00u2", MemberName: "b"},
+		{Time: at, Resource: "group", ResourceID: "00g1", Action: Removed, MemberID: "00u1", MemberName: "a"},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %+v, want %+v", events, want)
+	}
+	if len(errs) != 1 {
+		t.Errorf("errors = %v, want one", errs)
+	}
+}